@@ -61,7 +61,7 @@ func (c *Controller) processNextItem() bool {
 	// parallel.
 	defer c.queue.Done(key)
 
-	if key, ok := key.(string); ok {
+	if _, ok := key.(string); !ok {
 		klog.Info("skipping processing route with name ", " key ", key)
 		return true
 	}
@@ -77,7 +77,7 @@ func (c *Controller) processNextItem() bool {
 // information about the pod to stdout. In case an error happened, it has to simply return the error.
 // The retry logic should not be part of the business logic.
 func (c *Controller) syncToStdout(key string) error {
-	obj, exists, err := c.indexer.Get(key)
+	obj, exists, err := c.indexer.GetByKey(key)
 	if err != nil {
 		klog.Errorf("Fetching object with key %s from store failed with %v", key, err)
 		return err
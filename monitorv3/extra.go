@@ -2,10 +2,6 @@ package monitorv3
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
 	"fmt"
 	"reflect"
 	"testing"
@@ -225,85 +221,6 @@ func TestMonitorr(t *testing.T) {
 // 	}
 // }
 
-// ---------------------------------------------------------------
-type blockVerifierFunc func(block *pem.Block) (*pem.Block, error)
-
-func publicKeyBlockVerifier(block *pem.Block) (*pem.Block, error) {
-	key, err := x509.ParsePKIXPublicKey(block.Bytes)
-	if err != nil {
-		return nil, err
-	}
-	block = &pem.Block{
-		Type: "PUBLIC KEY",
-	}
-	if block.Bytes, err = x509.MarshalPKIXPublicKey(key); err != nil {
-		return nil, err
-	}
-	return block, nil
-}
-
-func certificateBlockVerifier(block *pem.Block) (*pem.Block, error) {
-	cert, err := x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return nil, err
-	}
-	block = &pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: cert.Raw,
-	}
-	return block, nil
-}
-
-func privateKeyBlockVerifier(block *pem.Block) (*pem.Block, error) {
-	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
-	if err != nil {
-		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
-		if err != nil {
-			key, err = x509.ParseECPrivateKey(block.Bytes)
-			if err != nil {
-				return nil, fmt.Errorf("block %s is not valid", block.Type)
-			}
-		}
-	}
-	switch t := key.(type) {
-	case *rsa.PrivateKey:
-		block = &pem.Block{
-			Type:  "RSA PRIVATE KEY",
-			Bytes: x509.MarshalPKCS1PrivateKey(t),
-		}
-	case *ecdsa.PrivateKey:
-		block = &pem.Block{
-			Type: "ECDSA PRIVATE KEY",
-		}
-		if block.Bytes, err = x509.MarshalECPrivateKey(t); err != nil {
-			return nil, err
-		}
-	default:
-		return nil, fmt.Errorf("block private key %T is not valid", key)
-	}
-	return block, nil
-}
-
-func ignoreBlockVerifier(block *pem.Block) (*pem.Block, error) {
-	return nil, nil
-}
-
-var knownBlockDecoders = map[string]blockVerifierFunc{
-	"RSA PRIVATE KEY":   privateKeyBlockVerifier,
-	"ECDSA PRIVATE KEY": privateKeyBlockVerifier,
-	"PRIVATE KEY":       privateKeyBlockVerifier,
-	"PUBLIC KEY":        publicKeyBlockVerifier,
-	// Potential "in the wild" PEM encoded blocks that can be normalized
-	"RSA PUBLIC KEY":   publicKeyBlockVerifier,
-	"DSA PUBLIC KEY":   publicKeyBlockVerifier,
-	"ECDSA PUBLIC KEY": publicKeyBlockVerifier,
-	"CERTIFICATE":      certificateBlockVerifier,
-	// Blocks that should be dropped
-	"EC PARAMETERS": ignoreBlockVerifier,
-}
-
-// ---------------------------------------------------------------
-
 func TestGetSecrettt(t *testing.T) {
 	var (
 		secretName = "secretName"
@@ -0,0 +1,166 @@
+package secret
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// coalescingHandler delays the events of the secrets of a parent by a window, and merges all events
+// received within the window into a single notification of handler. If several secrets of the parent
+// changed within the window, e.g. its certificate and CA bundle were rotated together, the notification
+// carries the final state of the secret which changed last; the handler reads the others from the cache.
+// Adds of the initial list are delivered right away.
+type coalescingHandler struct {
+	window time.Duration
+
+	lock    sync.Mutex
+	handler cache.ResourceEventHandler
+	// pending are the merged events waiting for the window to pass, keyed by secret
+	pending map[ObjectKey]*coalescedEvent
+	// sequence orders the pending events by their last change
+	sequence uint64
+	timer    *time.Timer
+	stopped  bool
+	// refs is the number of secret registrations sharing the handler, guarded by Manager.coalescersLock
+	refs int
+}
+
+// coalescedEvent is the merge of the events of a secret received within a window.
+type coalescedEvent struct {
+	// first is the type of the first event, and oldest the secret before it
+	first  watch.EventType
+	oldest interface{}
+	// last is the type of the last event, and latest the secret after it
+	last   watch.EventType
+	latest interface{}
+	// sequence is the sequence of the handler when the last event was merged
+	sequence uint64
+}
+
+func newCoalescingHandler(handler cache.ResourceEventHandler, window time.Duration) *coalescingHandler {
+	return &coalescingHandler{
+		handler: handler,
+		window:  window,
+		pending: map[ObjectKey]*coalescedEvent{},
+	}
+}
+
+// setHandler replaces the handler notified about the events, e.g. when the parent is registered again.
+func (c *coalescingHandler) setHandler(handler cache.ResourceEventHandler) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.handler = handler
+}
+
+// add merges the event into the pending one of its secret, and starts the window if there is none.
+func (c *coalescingHandler) add(eventType watch.EventType, oldObj, newObj interface{}) {
+	key, _, err := objectVersion(newObj)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.stopped {
+		return
+	}
+	c.sequence++
+	if event, exists := c.pending[key]; exists {
+		event.last = eventType
+		event.latest = newObj
+		event.sequence = c.sequence
+		return
+	}
+	c.pending[key] = &coalescedEvent{
+		first:    eventType,
+		oldest:   oldObj,
+		last:     eventType,
+		latest:   newObj,
+		sequence: c.sequence,
+	}
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.window, c.flush)
+	}
+}
+
+// flush notifies handler about the pending event of the secret which changed last.
+func (c *coalescingHandler) flush() {
+	c.lock.Lock()
+	var event *coalescedEvent
+	for _, e := range c.pending {
+		if event == nil || e.sequence > event.sequence {
+			event = e
+		}
+	}
+	c.pending = map[ObjectKey]*coalescedEvent{}
+	c.timer = nil
+	stopped := c.stopped
+	handler := c.handler
+	c.lock.Unlock()
+	if event == nil || stopped {
+		return
+	}
+
+	switch {
+	case event.last == watch.Deleted:
+		handler.OnDelete(event.latest)
+	case event.first == watch.Added:
+		handler.OnAdd(event.latest, false)
+	default:
+		// the secret may have been deleted and re-created within the window
+		if tombstone, ok := event.oldest.(cache.DeletedFinalStateUnknown); ok {
+			event.oldest = tombstone.Obj
+		}
+		handler.OnUpdate(event.oldest, event.latest)
+	}
+}
+
+// drop drops the pending event of the secret, e.g. once it is no longer referenced by the parent.
+func (c *coalescingHandler) drop(secret ObjectKey) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.pending, secret)
+	if len(c.pending) == 0 && c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+}
+
+// stop drops the pending events, and ignores all further events.
+func (c *coalescingHandler) stop() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.stopped = true
+	c.pending = map[ObjectKey]*coalescedEvent{}
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+}
+
+func (c *coalescingHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	if isInInitialList {
+		c.lock.Lock()
+		handler := c.handler
+		c.lock.Unlock()
+		handler.OnAdd(obj, isInInitialList)
+		return
+	}
+	c.add(watch.Added, nil, obj)
+}
+
+func (c *coalescingHandler) OnUpdate(oldObj, newObj interface{}) {
+	c.add(watch.Modified, oldObj, newObj)
+}
+
+func (c *coalescingHandler) OnDelete(obj interface{}) {
+	c.add(watch.Deleted, obj, obj)
+}
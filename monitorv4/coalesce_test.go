@@ -0,0 +1,269 @@
+package secret
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// notification is an event delivered to a recordingHandler, with the resourceVersions of its secrets.
+type notification struct {
+	eventType          watch.EventType
+	oldResourceVersion string
+	newResourceVersion string
+}
+
+// recordingHandler records the notifications it receives.
+type recordingHandler struct {
+	lock          sync.Mutex
+	notifications []notification
+}
+
+func (h *recordingHandler) record(eventType watch.EventType, oldObj, newObj interface{}) {
+	n := notification{eventType: eventType}
+	if oldObj != nil {
+		_, n.oldResourceVersion, _ = objectVersion(oldObj)
+	}
+	if newObj != nil {
+		_, n.newResourceVersion, _ = objectVersion(newObj)
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.notifications = append(h.notifications, n)
+}
+
+func (h *recordingHandler) get() []notification {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return append([]notification{}, h.notifications...)
+}
+
+func (h *recordingHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	h.record(watch.Added, nil, obj)
+}
+
+func (h *recordingHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.record(watch.Modified, oldObj, newObj)
+}
+
+func (h *recordingHandler) OnDelete(obj interface{}) {
+	h.record(watch.Deleted, obj, nil)
+}
+
+func TestCoalescingHandler(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		v         = func(resourceVersion string) interface{} { return fakeSecretVersion(namespace, "tls", resourceVersion) }
+	)
+
+	scenarios := []struct {
+		name   string
+		notify func(cache.ResourceEventHandler)
+		expect []notification
+	}{
+		{
+			name: "updates are merged into one update with the final state",
+			notify: func(h cache.ResourceEventHandler) {
+				h.OnUpdate(v("1"), v("2"))
+				h.OnUpdate(v("2"), v("3"))
+				h.OnUpdate(v("3"), v("4"))
+			},
+			expect: []notification{{eventType: watch.Modified, oldResourceVersion: "1", newResourceVersion: "4"}},
+		},
+		{
+			name: "add followed by updates is an add of the final state",
+			notify: func(h cache.ResourceEventHandler) {
+				h.OnAdd(v("1"), false)
+				h.OnUpdate(v("1"), v("2"))
+			},
+			expect: []notification{{eventType: watch.Added, newResourceVersion: "2"}},
+		},
+		{
+			name: "updates followed by a delete are a delete",
+			notify: func(h cache.ResourceEventHandler) {
+				h.OnUpdate(v("1"), v("2"))
+				h.OnDelete(v("2"))
+			},
+			expect: []notification{{eventType: watch.Deleted, oldResourceVersion: "2"}},
+		},
+		{
+			name: "delete followed by a re-create is an update",
+			notify: func(h cache.ResourceEventHandler) {
+				h.OnDelete(cache.DeletedFinalStateUnknown{Key: "sandbox/tls", Obj: v("1")})
+				h.OnAdd(v("2"), false)
+			},
+			expect: []notification{{eventType: watch.Modified, oldResourceVersion: "1", newResourceVersion: "2"}},
+		},
+		{
+			name: "events of several secrets are merged into one notification of the secret which changed last",
+			notify: func(h cache.ResourceEventHandler) {
+				h.OnUpdate(v("1"), v("2"))
+				h.OnUpdate(fakeSecretVersion(namespace, "ca", "1"), fakeSecretVersion(namespace, "ca", "5"))
+				h.OnUpdate(v("2"), v("3"))
+				h.OnUpdate(fakeSecretVersion(namespace, "ca", "5"), fakeSecretVersion(namespace, "ca", "6"))
+			},
+			expect: []notification{{eventType: watch.Modified, oldResourceVersion: "1", newResourceVersion: "6"}},
+		},
+		{
+			name: "initial list adds are not delayed",
+			notify: func(h cache.ResourceEventHandler) {
+				h.OnAdd(v("1"), true)
+			},
+			expect: []notification{{eventType: watch.Added, newResourceVersion: "1"}},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			recorder := &recordingHandler{}
+			c := newCoalescingHandler(recorder, 100*time.Millisecond)
+			s.notify(c)
+
+			err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+				return len(recorder.get()) >= len(s.expect), nil
+			})
+			if err != nil {
+				t.Fatalf("expected %d notifications, got %v", len(s.expect), recorder.get())
+			}
+			// no further notification follows once no events wait for the window
+			if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+				return c.idle(), nil
+			}); err != nil {
+				t.Fatal("expected no events waiting for the window")
+			}
+			got := recorder.get()
+			if len(got) != len(s.expect) {
+				t.Fatalf("expected notifications %+v, got %+v", s.expect, got)
+			}
+			for i := range got {
+				if got[i] != s.expect[i] {
+					t.Errorf("expected notification %+v, got %+v", s.expect[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+// idle returns true if no events wait for the window.
+func (c *coalescingHandler) idle() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.pending) == 0 && c.timer == nil
+}
+
+// pendingVersion returns the resourceVersion of the event of the secret waiting in the coalescer
+// of the parent, or "" if there is none.
+func pendingVersion(m *Manager, parent ParentKey, secret ObjectKey) string {
+	m.coalescersLock.Lock()
+	defer m.coalescersLock.Unlock()
+	c, exists := m.coalescers[parent]
+	if !exists {
+		return ""
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	event, exists := c.pending[secret]
+	if !exists {
+		return ""
+	}
+	_, version, _ := objectVersion(event.latest)
+	return version
+}
+
+func TestManagerCoalescingWindow(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		routeName = "route"
+		route     = NewRouteKey(namespace, routeName)
+	)
+
+	fakeKubeClient := fake.NewSimpleClientset(fakeSecretVersion(namespace, "tls", "1"), fakeSecretVersion(namespace, "ca", "1"))
+	m := fakeManager(fakeKubeClient).WithCoalescingWindow(200 * time.Millisecond)
+	recorder := &recordingHandler{}
+
+	if err := m.RegisterRouteSecrets(context.TODO(), namespace, routeName, "tls", sets.NewString("tls", "ca"), recorder); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range m.registeredHandlers[route] {
+		if !cache.WaitForCacheSync(context.TODO().Done(), r.HasSynced) {
+			t.Fatal("handler failed to sync")
+		}
+	}
+	m.coalescersLock.Lock()
+	if n := len(m.coalescers); n != 1 {
+		t.Errorf("expected one coalescer shared by the secrets of the route, got %d", n)
+	}
+	m.coalescersLock.Unlock()
+	initial := len(recorder.get())
+
+	// rotate the certificate, then its annotations, then the CA bundle. Each secret has an informer of
+	// its own, so every update is awaited in the coalescer before the next one is made.
+	for _, secret := range []*corev1.Secret{
+		fakeSecretVersion(namespace, "tls", "2"),
+		fakeSecretVersion(namespace, "tls", "3"),
+		fakeSecretVersion(namespace, "ca", "5"),
+	} {
+		if _, err := fakeKubeClient.CoreV1().Secrets(namespace).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := wait.PollImmediate(time.Millisecond, time.Second, func() (bool, error) {
+			return pendingVersion(m, route, NewObjectKey(namespace, secret.Name)) == secret.ResourceVersion, nil
+		}); err != nil {
+			t.Fatalf("expected update of %s to resourceVersion %s waiting for the window", secret.Name, secret.ResourceVersion)
+		}
+	}
+
+	expect := notification{eventType: watch.Modified, oldResourceVersion: "1", newResourceVersion: "5"}
+	err := wait.PollImmediate(10*time.Millisecond, 2*time.Second, func() (bool, error) {
+		return len(recorder.get()) > initial, nil
+	})
+	if err != nil {
+		t.Fatalf("expected notification %+v, got %+v", expect, recorder.get()[initial:])
+	}
+	m.coalescersLock.Lock()
+	c := m.coalescers[route]
+	m.coalescersLock.Unlock()
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return c.idle(), nil
+	}); err != nil {
+		t.Fatal("expected no events waiting for the window")
+	}
+	if got := recorder.get()[initial:]; len(got) != 1 || got[0] != expect {
+		t.Errorf("expected a single notification %+v, got %+v", expect, got)
+	}
+	notified := len(recorder.get())
+
+	// events waiting for the window are dropped with the registration
+	secret := fakeSecretVersion(namespace, "tls", "4")
+	if _, err := fakeKubeClient.CoreV1().Secrets(namespace).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return !c.idle(), nil
+	}); err != nil {
+		t.Fatal("expected an update waiting for the window")
+	}
+	if err := m.UnregisterRoute(namespace, routeName); err != nil {
+		t.Fatal(err)
+	}
+	if !c.idle() {
+		t.Error("expected the update waiting for the window to be dropped")
+	}
+	if n := len(recorder.get()); n != notified {
+		t.Errorf("expected no notification after unregistering, got %+v", recorder.get()[notified:])
+	}
+	m.coalescersLock.Lock()
+	defer m.coalescersLock.Unlock()
+	if n := len(m.coalescers); n != 0 {
+		t.Errorf("expected the coalescer to be dropped with the route, got %d", n)
+	}
+}
@@ -0,0 +1,35 @@
+package secret
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NewDynamicObjectMonitor returns an ObjectMonitor which watches single objects of an arbitrary
+// resource, including custom resources, using the dynamic client. Handlers receive *unstructured.Unstructured.
+func NewDynamicObjectMonitor(dynamicClient dynamic.Interface, gvr schema.GroupVersionResource) ObjectMonitor[*unstructured.Unstructured] {
+	return newObjectMonitor[*unstructured.Unstructured](gvr.GroupResource(), createDynamicInformer(dynamicClient, gvr))
+}
+
+// createDynamicInformer returns a function which creates a dynamic SharedInformer
+// for monitoring a specific object of the given resource.
+func createDynamicInformer(dynamicClient dynamic.Interface, gvr schema.GroupVersionResource) func(namespace, name string) cache.SharedInformer {
+	return func(namespace, name string) cache.SharedInformer {
+		fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+		return dynamicinformer.NewFilteredDynamicInformer(
+			dynamicClient,
+			gvr,
+			namespace,
+			0,
+			cache.Indexers{},
+			func(options *metav1.ListOptions) {
+				options.FieldSelector = fieldSelector
+			},
+		).Informer()
+	}
+}
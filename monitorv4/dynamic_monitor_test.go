@@ -0,0 +1,98 @@
+package secret
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+var certificateGVR = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+
+func fakeCertificate(namespace, name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "cert-manager.io/v1",
+			"kind":       "Certificate",
+			"metadata": map[string]interface{}{
+				"namespace": namespace,
+				"name":      name,
+			},
+			"spec": map[string]interface{}{
+				"secretName": "tls",
+			},
+		},
+	}
+}
+
+func TestDynamicObjectMonitor(t *testing.T) {
+	var (
+		namespace   = "ns"
+		name        = "certificate"
+		certificate = fakeCertificate(namespace, name)
+	)
+
+	scenarios := []struct {
+		name              string
+		withCertificate   bool
+		expectCertificate *unstructured.Unstructured
+		expectErr         bool
+	}{
+		{
+			name:              "custom resource does not exist in cluster",
+			withCertificate:   false,
+			expectCertificate: nil,
+			expectErr:         true,
+		},
+		{
+			name:              "custom resource exists in cluster",
+			withCertificate:   true,
+			expectCertificate: certificate,
+			expectErr:         false,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if s.withCertificate {
+				objects = append(objects, certificate.DeepCopy())
+			}
+			fakeDynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+				runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{certificateGVR: "CertificateList"},
+				objects...,
+			)
+			key := NewObjectKey(namespace, name)
+			om := NewDynamicObjectMonitor(fakeDynamicClient, certificateGVR).(*objectMonitor[*unstructured.Unstructured])
+
+			h, err := om.AddEventHandler(context.TODO(), namespace, name, cache.ResourceEventHandlerFuncs{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, gotErr := om.Get(h)
+			if gotErr != nil && !s.expectErr {
+				t.Errorf("unexpected error %v", gotErr)
+			}
+			if gotErr == nil && s.expectErr {
+				t.Errorf("expecting an error, got nil")
+			}
+			if !reflect.DeepEqual(s.expectCertificate, got) {
+				t.Errorf("expected %v got %v", s.expectCertificate, got)
+			}
+
+			if err := om.RemoveEventHandler(h); err != nil {
+				t.Error(err)
+			}
+			if _, exist := om.monitors[key]; exist {
+				t.Error("monitor key should be removed from map", key)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+)
+
+// DefaultSyncTimeout is the default time to wait for an informer cache to sync.
+const DefaultSyncTimeout = 30 * time.Second
+
+// syncPollPeriod is how often the informer is checked while waiting for it to sync.
+const syncPollPeriod = 100 * time.Millisecond
+
+// SyncFailureReason describes why an informer cache failed to sync.
+type SyncFailureReason string
+
+const (
+	// SyncTimeout means the cache did not sync before the context was done or the deadline passed.
+	SyncTimeout SyncFailureReason = "SyncTimeout"
+	// SyncForbidden means listing or watching the object is forbidden.
+	SyncForbidden SyncFailureReason = "Forbidden"
+)
+
+// SyncError is returned when an informer cache fails to sync.
+// It wraps the last list/watch error of the informer, if any.
+type SyncError struct {
+	// Key is the key of the object being monitored.
+	Key ObjectKey
+	// Reason is why the cache failed to sync.
+	Reason SyncFailureReason
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *SyncError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("failed waiting for cache sync of %v: %s", e.Key, e.Reason)
+	}
+	return fmt.Sprintf("failed waiting for cache sync of %v: %s: %v", e.Key, e.Reason, e.Err)
+}
+
+func (e *SyncError) Unwrap() error {
+	return e.Err
+}
+
+// ReasonForSyncError returns the SyncFailureReason of err, or "" if err is not a SyncError.
+func ReasonForSyncError(err error) SyncFailureReason {
+	var syncErr *SyncError
+	if errors.As(err, &syncErr) {
+		return syncErr.Reason
+	}
+	return ""
+}
+
+// IsSyncTimeout returns true if err is a SyncError caused by a timeout.
+func IsSyncTimeout(err error) bool {
+	return ReasonForSyncError(err) == SyncTimeout
+}
+
+// IsSyncForbidden returns true if err is a SyncError caused by missing permissions.
+func IsSyncForbidden(err error) bool {
+	return ReasonForSyncError(err) == SyncForbidden
+}
+
+// waitForSync waits until hasSynced returns true, ctx is done or timeout passes.
+// It fails early when lastError reports that listing is forbidden, since retrying will not fix it on its own.
+// A missing namespace is not detected: listing the objects of a missing namespace returns an empty list,
+// so the informer syncs, and the object is reported as not found.
+func waitForSync(ctx context.Context, key ObjectKey, timeout time.Duration, hasSynced cache.InformerSynced, lastError func() error) error {
+	err := wait.PollUntilContextTimeout(ctx, syncPollPeriod, timeout, true, func(context.Context) (bool, error) {
+		if hasSynced() {
+			return true, nil
+		}
+		if err := lastError(); apierrors.IsForbidden(err) {
+			return false, &SyncError{Key: key, Reason: SyncForbidden, Err: err}
+		}
+		return false, nil
+	})
+	if err == nil || ReasonForSyncError(err) != "" {
+		return err
+	}
+
+	// report the last list/watch error, which is more useful than the context error
+	if lastErr := lastError(); lastErr != nil {
+		err = lastErr
+	}
+	return &SyncError{Key: key, Reason: SyncTimeout, Err: err}
+}
@@ -0,0 +1,81 @@
+package secret
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// SecretChangeEvent is a change of a secret referenced by a parent. Since every change carries its own
+// resourceVersions, the queue doesn't collapse the changes of a secret: each of them is processed once.
+type SecretChangeEvent struct {
+	// Parent is the key of the parent referencing the secret.
+	Parent ParentKey
+	// Secret is the key of the changed secret.
+	Secret ObjectKey
+	// Type is watch.Added, watch.Modified or watch.Deleted.
+	Type watch.EventType
+	// OldResourceVersion is the resourceVersion before the change. Empty for watch.Added.
+	OldResourceVersion string
+	// NewResourceVersion is the resourceVersion after the change. Empty for watch.Deleted.
+	NewResourceVersion string
+}
+
+// EnqueueChangeEventsHandler returns a secret handler for the parent, which adds a SecretChangeEvent
+// to the Manager's queue for every event of the secret, so that controllers only have to consume
+// the queue instead of handling the secret events themselves.
+func (m *Manager) EnqueueChangeEventsHandler(parent ParentKey) cache.ResourceEventHandler {
+	return changeEventEnqueuer{manager: m, parent: parent}
+}
+
+// changeEventEnqueuer is the secret handler enqueueing the SecretChangeEvents of a parent.
+type changeEventEnqueuer struct {
+	manager *Manager
+	parent  ParentKey
+}
+
+// objectVersion returns the key and resourceVersion of obj, which may be a tombstone.
+func objectVersion(obj interface{}) (ObjectKey, string, error) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return ObjectKey{}, "", err
+	}
+	return NewObjectKey(accessor.GetNamespace(), accessor.GetName()), accessor.GetResourceVersion(), nil
+}
+
+func (e changeEventEnqueuer) enqueue(eventType watch.EventType, oldObj, newObj interface{}) {
+	event := SecretChangeEvent{
+		Parent: e.parent,
+		Type:   eventType,
+	}
+	var err error
+	if oldObj != nil {
+		if event.Secret, event.OldResourceVersion, err = objectVersion(oldObj); err != nil {
+			klog.Error(err)
+			return
+		}
+	}
+	if newObj != nil {
+		if event.Secret, event.NewResourceVersion, err = objectVersion(newObj); err != nil {
+			klog.Error(err)
+			return
+		}
+	}
+	e.manager.resourceChanges.Add(event)
+}
+
+func (e changeEventEnqueuer) OnAdd(obj interface{}, isInInitialList bool) {
+	e.enqueue(watch.Added, nil, obj)
+}
+
+func (e changeEventEnqueuer) OnUpdate(oldObj, newObj interface{}) {
+	e.enqueue(watch.Modified, oldObj, newObj)
+}
+
+func (e changeEventEnqueuer) OnDelete(obj interface{}) {
+	e.enqueue(watch.Deleted, obj, nil)
+}
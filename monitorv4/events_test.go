@@ -0,0 +1,103 @@
+package secret
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestEnqueueChangeEventsHandler(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		route     = NewRouteKey(namespace, "route")
+		tls       = NewObjectKey(namespace, "tls")
+	)
+
+	scenarios := []struct {
+		name   string
+		notify func(cache.ResourceEventHandler)
+		expect []SecretChangeEvent
+	}{
+		{
+			name: "add carries the new resourceVersion",
+			notify: func(h cache.ResourceEventHandler) {
+				h.OnAdd(fakeSecretVersion(namespace, "tls", "1"), false)
+			},
+			expect: []SecretChangeEvent{
+				{Parent: route, Secret: tls, Type: watch.Added, NewResourceVersion: "1"},
+			},
+		},
+		{
+			name: "update carries both resourceVersions",
+			notify: func(h cache.ResourceEventHandler) {
+				h.OnUpdate(fakeSecretVersion(namespace, "tls", "1"), fakeSecretVersion(namespace, "tls", "2"))
+			},
+			expect: []SecretChangeEvent{
+				{Parent: route, Secret: tls, Type: watch.Modified, OldResourceVersion: "1", NewResourceVersion: "2"},
+			},
+		},
+		{
+			name: "delete of a tombstone carries the last known resourceVersion",
+			notify: func(h cache.ResourceEventHandler) {
+				h.OnDelete(cache.DeletedFinalStateUnknown{Key: "sandbox/tls", Obj: fakeSecretVersion(namespace, "tls", "2")})
+			},
+			expect: []SecretChangeEvent{
+				{Parent: route, Secret: tls, Type: watch.Deleted, OldResourceVersion: "2"},
+			},
+		},
+		{
+			name: "successive changes are not collapsed by the queue",
+			notify: func(h cache.ResourceEventHandler) {
+				h.OnUpdate(fakeSecretVersion(namespace, "tls", "1"), fakeSecretVersion(namespace, "tls", "2"))
+				h.OnUpdate(fakeSecretVersion(namespace, "tls", "2"), fakeSecretVersion(namespace, "tls", "3"))
+			},
+			expect: []SecretChangeEvent{
+				{Parent: route, Secret: tls, Type: watch.Modified, OldResourceVersion: "1", NewResourceVersion: "2"},
+				{Parent: route, Secret: tls, Type: watch.Modified, OldResourceVersion: "2", NewResourceVersion: "3"},
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			m := fakeManager(fake.NewSimpleClientset())
+			s.notify(m.EnqueueChangeEventsHandler(route))
+
+			if n := m.Queue().Len(); n != len(s.expect) {
+				t.Fatalf("expected %d events, got %d", len(s.expect), n)
+			}
+			for _, expect := range s.expect {
+				item, _ := m.Queue().Get()
+				if item != expect {
+					t.Errorf("expected event %+v, got %+v", expect, item)
+				}
+				m.Queue().Done(item)
+			}
+		})
+	}
+}
+
+func TestManagerEnqueuesChangeEvents(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		route     = NewRouteKey(namespace, "route")
+	)
+
+	fakeKubeClient := fake.NewSimpleClientset(fakeSecretVersion(namespace, "tls", "1"))
+	m := fakeManager(fakeKubeClient)
+
+	if err := m.RegisterRouteSecrets(context.TODO(), namespace, "route", "tls", sets.NewString("tls"), m.EnqueueChangeEventsHandler(route)); err != nil {
+		t.Fatal(err)
+	}
+
+	item, _ := m.Queue().Get()
+	expect := SecretChangeEvent{Parent: route, Secret: NewObjectKey(namespace, "tls"), Type: watch.Added, NewResourceVersion: "1"}
+	if item != expect {
+		t.Errorf("expected event %+v, got %+v", expect, item)
+	}
+	m.Queue().Done(item)
+}
@@ -0,0 +1,217 @@
+package secret
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// DefaultExpiryThresholds are the times before the expiry of a certificate at which the parents
+// referencing it are commonly requeued, for use with WithExpiryThresholds.
+var DefaultExpiryThresholds = []time.Duration{30 * 24 * time.Hour, 7 * 24 * time.Hour, 24 * time.Hour}
+
+// CertificateExpiry is the expiry of the leaf certificate of a secret referenced by a parent.
+type CertificateExpiry struct {
+	// Parent is the key of the parent referencing the secret.
+	Parent ParentKey
+	// Secret is the key of the secret holding the certificate.
+	Secret ObjectKey
+	// NotAfter is when the leaf certificate expires.
+	NotAfter time.Time
+}
+
+// WithExpiryThresholds makes the Manager requeue the parents referencing a certificate once each of the
+// thresholds before its expiry is reached, and at its expiry. Without thresholds, parents are only requeued
+// at expiry. The requeues add the ParentKey of the parent to the queue, like EnqueueParentsHandler, so they
+// must not be enabled for a queue of SecretChangeEvents. Parents are not requeued unless this is called.
+// It must be called before any parent is registered.
+func (m *Manager) WithExpiryThresholds(thresholds ...time.Duration) *Manager {
+	m.requeueExpiring = true
+	m.expiryThresholds = thresholds
+	return m
+}
+
+// ExpiringCertificates returns the certificates of all registrations expiring within the window
+// from now, including the expired ones, sorted by expiry.
+func (m *Manager) ExpiringCertificates(window time.Duration) []CertificateExpiry {
+	deadline := time.Now().Add(window)
+
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	expiries := []CertificateExpiry{}
+	for parent, registrations := range m.registeredHandlers {
+		for secret, handlerRegistration := range registrations {
+			notAfter := m.notAfter(handlerRegistration)
+			if notAfter.IsZero() || notAfter.After(deadline) {
+				continue
+			}
+			expiries = append(expiries, CertificateExpiry{
+				Parent:   parent,
+				Secret:   secret,
+				NotAfter: notAfter,
+			})
+		}
+	}
+	sort.Slice(expiries, func(i, j int) bool {
+		if !expiries[i].NotAfter.Equal(expiries[j].NotAfter) {
+			return expiries[i].NotAfter.Before(expiries[j].NotAfter)
+		}
+		return lessParentKey(expiries[i].Parent, expiries[j].Parent)
+	})
+	return expiries
+}
+
+// notAfter returns the expiry of the certificate of the registration, zero if unknown.
+func (m *Manager) notAfter(handlerRegistration SecretEventHandlerRegistration) time.Time {
+	m.expiriesLock.RLock()
+	tracker, exists := m.expiries[handlerRegistration]
+	m.expiriesLock.RUnlock()
+	if !exists {
+		return time.Time{}
+	}
+	return tracker.get()
+}
+
+// nextRequeue returns the delay until the first of the thresholds before notAfter, or notAfter itself,
+// which is yet to be reached. False if all have passed.
+func (m *Manager) nextRequeue(notAfter time.Time) (time.Duration, bool) {
+	var next time.Duration
+	found := false
+	for _, threshold := range append([]time.Duration{0}, m.expiryThresholds...) {
+		if delay := time.Until(notAfter.Add(-threshold)); delay > 0 && (!found || delay < next) {
+			next, found = delay, true
+		}
+	}
+	return next, found
+}
+
+// leafNotAfter returns the expiry of the leaf certificate in tls.crt of the secret, zero if there is none.
+func leafNotAfter(secret *v1.Secret) time.Time {
+	for rest := secret.Data[v1.TLSCertKey]; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			return time.Time{}
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}
+		}
+		return cert.NotAfter
+	}
+}
+
+// expiryTracker keeps the expiry of the certificate of a secret registered for a parent up to date,
+// and requeues the parent as the thresholds before the expiry are reached, if enabled by WithExpiryThresholds.
+// All events are forwarded to handler.
+//
+// The thresholds are chained by a single timer rather than queued up front with AddAfter, since the
+// delaying queue only keeps the earliest pending AddAfter of an item, and cannot cancel it once the
+// parent is unregistered. The timer is stopped when the registration is removed.
+type expiryTracker struct {
+	manager *Manager
+	parent  ParentKey
+	handler cache.ResourceEventHandler
+
+	lock     sync.RWMutex
+	notAfter time.Time
+	timer    *time.Timer
+	stopped  bool
+}
+
+func newExpiryTracker(manager *Manager, parent ParentKey, handler cache.ResourceEventHandler) *expiryTracker {
+	return &expiryTracker{
+		manager: manager,
+		parent:  parent,
+		handler: handler,
+	}
+}
+
+func (e *expiryTracker) get() time.Time {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	return e.notAfter
+}
+
+// set records notAfter, and reschedules the requeues if it changed.
+func (e *expiryTracker) set(notAfter time.Time) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if notAfter.Equal(e.notAfter) || e.stopped {
+		return
+	}
+	e.notAfter = notAfter
+	e.schedule()
+}
+
+// schedule arms the timer for the next requeue of the parent. Must be called with the lock held.
+func (e *expiryTracker) schedule() {
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	if e.notAfter.IsZero() || !e.manager.requeueExpiring {
+		return
+	}
+	delay, ok := e.manager.nextRequeue(e.notAfter)
+	if !ok {
+		return
+	}
+	notAfter := e.notAfter
+	e.timer = time.AfterFunc(delay, func() {
+		e.lock.Lock()
+		defer e.lock.Unlock()
+		// the certificate was rotated, or the tracker stopped, while the timer fired
+		if e.stopped || !notAfter.Equal(e.notAfter) {
+			return
+		}
+		klog.Info(fmt.Sprintf("secret manager requeued %v, certificate expires at %v", e.parent, notAfter))
+		e.manager.resourceChanges.Add(e.parent)
+		e.schedule()
+	})
+}
+
+// stop cancels the pending requeue.
+func (e *expiryTracker) stop() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.stopped = true
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+}
+
+// observe records the expiry of the certificate of obj.
+func (e *expiryTracker) observe(obj interface{}) {
+	if secret, ok := obj.(*v1.Secret); ok {
+		e.set(leafNotAfter(secret))
+	}
+}
+
+func (e *expiryTracker) OnAdd(obj interface{}, isInInitialList bool) {
+	e.observe(obj)
+	e.handler.OnAdd(obj, isInInitialList)
+}
+
+func (e *expiryTracker) OnUpdate(oldObj, newObj interface{}) {
+	e.observe(newObj)
+	e.handler.OnUpdate(oldObj, newObj)
+}
+
+func (e *expiryTracker) OnDelete(obj interface{}) {
+	e.set(time.Time{})
+	e.handler.OnDelete(obj)
+}
@@ -0,0 +1,282 @@
+package secret
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// fakeCertificateSecret returns a TLS secret whose certificate expires at notAfter.
+func fakeCertificateSecret(t *testing.T, namespace, name string, notAfter time.Time) *corev1.Secret {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := fakeSecret(namespace, name)
+	secret.Type = corev1.SecretTypeTLS
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		corev1.TLSPrivateKeyKey: []byte("key"),
+	}
+	return secret
+}
+
+func TestLeafNotAfter(t *testing.T) {
+	notAfter := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	withCertificate := fakeCertificateSecret(t, "sandbox", "tls", notAfter)
+
+	chain := withCertificate.DeepCopy()
+	chain.Data[corev1.TLSCertKey] = append(append([]byte{}, chain.Data[corev1.TLSCertKey]...),
+		fakeCertificateSecret(t, "sandbox", "ca", notAfter.Add(time.Hour)).Data[corev1.TLSCertKey]...)
+
+	afterKey := withCertificate.DeepCopy()
+	afterKey.Data[corev1.TLSCertKey] = append(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")}),
+		afterKey.Data[corev1.TLSCertKey]...)
+
+	malformed := withCertificate.DeepCopy()
+	malformed.Data[corev1.TLSCertKey] = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")})
+
+	scenarios := []struct {
+		name   string
+		secret *corev1.Secret
+		expect time.Time
+	}{
+		{
+			name:   "expiry of the certificate",
+			secret: withCertificate,
+			expect: notAfter,
+		},
+		{
+			name:   "expiry of the leaf of a chain",
+			secret: chain,
+			expect: notAfter,
+		},
+		{
+			name:   "blocks other than certificates are skipped",
+			secret: afterKey,
+			expect: notAfter,
+		},
+		{
+			name:   "malformed certificate has no expiry",
+			secret: malformed,
+		},
+		{
+			name:   "secret without certificate has no expiry",
+			secret: fakeSecret("sandbox", "opaque"),
+		},
+	}
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.name, func(t *testing.T) {
+			if got := leafNotAfter(s.secret); !got.Equal(s.expect) {
+				t.Errorf("expected %v, got %v", s.expect, got)
+			}
+		})
+	}
+}
+
+func TestManagerExpiringCertificates(t *testing.T) {
+	var (
+		frontend = NewRouteKey("sandbox", "frontend")
+		backend  = NewRouteKey("staging", "backend")
+		now      = time.Now().Truncate(time.Second)
+		soon     = now.Add(3 * time.Second)
+		later    = now.Add(48 * time.Hour)
+	)
+
+	// the secrets are in distinct namespaces, since the fake clientset ignores field selectors
+	fakeKubeClient := fake.NewSimpleClientset(
+		fakeCertificateSecret(t, "sandbox", "soon", soon),
+		fakeCertificateSecret(t, "staging", "later", later),
+	)
+	m := fakeManager(fakeKubeClient).WithExpiryThresholds(2 * time.Second)
+
+	if err := m.RegisterRouteSecrets(context.TODO(), "sandbox", "frontend", "soon", sets.NewString("soon"), cache.ResourceEventHandlerFuncs{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RegisterRouteSecrets(context.TODO(), "staging", "backend", "later", sets.NewString("later"), cache.ResourceEventHandlerFuncs{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, registrations := range m.registeredHandlers {
+		for _, r := range registrations {
+			if !cache.WaitForCacheSync(context.TODO().Done(), r.HasSynced) {
+				t.Fatal("handler failed to sync")
+			}
+		}
+	}
+
+	scenarios := []struct {
+		name   string
+		window time.Duration
+		expect []CertificateExpiry
+	}{
+		{
+			name:   "certificates expiring within the window",
+			window: time.Hour,
+			expect: []CertificateExpiry{
+				{Parent: frontend, Secret: NewObjectKey("sandbox", "soon"), NotAfter: soon},
+			},
+		},
+		{
+			name:   "certificates sorted by expiry",
+			window: 72 * time.Hour,
+			expect: []CertificateExpiry{
+				{Parent: frontend, Secret: NewObjectKey("sandbox", "soon"), NotAfter: soon},
+				{Parent: backend, Secret: NewObjectKey("staging", "later"), NotAfter: later},
+			},
+		},
+		{
+			name:   "no certificates expiring within the past",
+			window: -24 * time.Hour,
+			expect: []CertificateExpiry{},
+		},
+	}
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.name, func(t *testing.T) {
+			got := m.ExpiringCertificates(s.window)
+			if len(got) != len(s.expect) {
+				t.Fatalf("expected %v, got %v", s.expect, got)
+			}
+			for i := range got {
+				if !got[i].NotAfter.Equal(s.expect[i].NotAfter) {
+					t.Errorf("expected expiry %v, got %v", s.expect[i].NotAfter, got[i].NotAfter)
+				}
+				got[i].NotAfter = s.expect[i].NotAfter
+			}
+			if !reflect.DeepEqual(got, s.expect) {
+				t.Errorf("expected %v, got %v", s.expect, got)
+			}
+		})
+	}
+
+	registrations, err := m.Describe(frontend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(registrations) != 1 || !registrations[0].NotAfter.Equal(soon) {
+		t.Errorf("expected registration to expire at %v, got %v", soon, registrations)
+	}
+
+	// the frontend is requeued once the threshold is reached, and again at expiry
+	for _, expect := range []time.Time{soon.Add(-2 * time.Second), soon} {
+		item, _ := m.resourceChanges.Get()
+		if item != frontend {
+			t.Errorf("expected %v to be requeued, got %v", frontend, item)
+		}
+		if got := time.Now(); got.Before(expect) {
+			t.Errorf("expected requeue after %v, got %v", expect, got)
+		}
+		m.resourceChanges.Done(item)
+	}
+	if n := m.resourceChanges.Len(); n != 0 {
+		t.Errorf("expected no other requeue, got %d", n)
+	}
+}
+
+func TestManagerExpiryRequeueOptIn(t *testing.T) {
+	soon := time.Now().Add(time.Second)
+
+	fakeKubeClient := fake.NewSimpleClientset(fakeCertificateSecret(t, "sandbox", "soon", soon))
+	m := fakeManager(fakeKubeClient)
+
+	if err := m.RegisterRouteSecrets(context.TODO(), "sandbox", "frontend", "soon", sets.NewString("soon"), cache.ResourceEventHandlerFuncs{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the expiry is tracked, but the parent is not requeued without WithExpiryThresholds
+	if got := m.ExpiringCertificates(time.Hour); len(got) != 1 {
+		t.Errorf("expected one expiring certificate, got %v", got)
+	}
+	if tracker := expiryTrackerOf(m, NewRouteKey("sandbox", "frontend"), NewObjectKey("sandbox", "soon")); tracker.armed() {
+		t.Error("expected no requeue to be scheduled")
+	}
+}
+
+func TestManagerExpiryRequeueStoppedOnUnregister(t *testing.T) {
+	var (
+		frontend = NewRouteKey("sandbox", "frontend")
+		backend  = NewRouteKey("staging", "backend")
+		now      = time.Now().Truncate(time.Second)
+	)
+
+	// the secrets are in distinct namespaces, since the fake clientset ignores field selectors
+	fakeKubeClient := fake.NewSimpleClientset(
+		fakeCertificateSecret(t, "sandbox", "early", now.Add(2*time.Second)),
+		fakeCertificateSecret(t, "staging", "late", now.Add(3*time.Second)),
+	)
+	m := fakeManager(fakeKubeClient).WithExpiryThresholds()
+
+	if err := m.RegisterRouteSecrets(context.TODO(), "sandbox", "frontend", "early", sets.NewString("early"), cache.ResourceEventHandlerFuncs{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RegisterRouteSecrets(context.TODO(), "staging", "backend", "late", sets.NewString("late"), cache.ResourceEventHandlerFuncs{}); err != nil {
+		t.Fatal(err)
+	}
+	early := expiryTrackerOf(m, frontend, NewObjectKey("sandbox", "early"))
+	late := expiryTrackerOf(m, backend, NewObjectKey("staging", "late"))
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return early.armed() && late.armed(), nil
+	}); err != nil {
+		t.Fatal("expected requeues to be scheduled for both routes")
+	}
+
+	if err := m.UnregisterRoute("sandbox", "frontend"); err != nil {
+		t.Fatal(err)
+	}
+	if early.armed() {
+		t.Error("expected the requeue of the unregistered route to be cancelled")
+	}
+
+	// the frontend would have been requeued before the backend
+	item, _ := m.resourceChanges.Get()
+	if item != backend {
+		t.Errorf("expected %v to be requeued, got %v", backend, item)
+	}
+	m.resourceChanges.Done(item)
+	if n := m.resourceChanges.Len(); n != 0 {
+		t.Errorf("expected no other requeue, got %d", n)
+	}
+}
+
+// expiryTrackerOf returns the expiry tracker of the registration of the secret for the parent.
+func expiryTrackerOf(m *Manager, parent ParentKey, secret ObjectKey) *expiryTracker {
+	m.lock.RLock()
+	handlerRegistration := m.registeredHandlers[parent][secret]
+	m.lock.RUnlock()
+
+	m.expiriesLock.RLock()
+	defer m.expiriesLock.RUnlock()
+	return m.expiries[handlerRegistration]
+}
+
+// armed returns whether a requeue is scheduled.
+func (e *expiryTracker) armed() bool {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	return e.timer != nil
+}
@@ -0,0 +1,110 @@
+package secret
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// fakeSecretMonitor returns a secretMonitor whose secret and selector informers list/watch fakeKubeClient.
+func fakeSecretMonitor(fakeKubeClient *fake.Clientset) *secretMonitor {
+	sm := newSecretMonitor(fakeKubeClient)
+	sm.createInformer = func(namespace, name string) cache.SharedInformer {
+		return fakeSecretInformer(context.TODO(), fakeKubeClient, namespace, name)
+	}
+	sm.selectors.createInformer = fakeSelectorSecretInformer(context.TODO(), fakeKubeClient)
+	return sm
+}
+
+// fakeSecretInformer will list/watch only one secret inside a namespace
+func fakeSecretInformer(ctx context.Context, fakeKubeClient *fake.Clientset, namespace, name string) cache.SharedInformer {
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	klog.Info(fieldSelector)
+	return cache.NewSharedInformer(&cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return fakeKubeClient.CoreV1().Secrets(namespace).List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return fakeKubeClient.CoreV1().Secrets(namespace).Watch(ctx, options)
+		},
+	},
+		&corev1.Secret{},
+		0,
+	)
+}
+
+func fakeSecret(namespace, name string) *corev1.Secret {
+	return &corev1.Secret{
+		Type: corev1.SecretTypeOpaque,
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			"test": {1, 2, 3, 4},
+		},
+	}
+}
+
+// fakeSecretVersion returns a secret with the given resourceVersion.
+func fakeSecretVersion(namespace, name, resourceVersion string) *corev1.Secret {
+	secret := fakeSecret(namespace, name)
+	secret.ResourceVersion = resourceVersion
+	return secret
+}
+
+func fakeLabelledSecret(namespace, name string, secretLabels map[string]string) *corev1.Secret {
+	secret := fakeSecret(namespace, name)
+	secret.Labels = secretLabels
+	return secret
+}
+
+// fakeNamespaceSecretInformer will list/watch all secrets inside a namespace
+func fakeNamespaceSecretInformer(ctx context.Context, fakeKubeClient *fake.Clientset) func(namespace string) cache.SharedInformer {
+	return func(namespace string) cache.SharedInformer {
+		return cache.NewSharedInformer(&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return fakeKubeClient.CoreV1().Secrets(namespace).List(ctx, options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return fakeKubeClient.CoreV1().Secrets(namespace).Watch(ctx, options)
+			},
+		},
+			&corev1.Secret{},
+			0,
+		)
+	}
+}
+
+// fakeSelectorSecretInformer will list/watch the secrets matching a label selector inside a namespace
+func fakeSelectorSecretInformer(ctx context.Context, fakeKubeClient *fake.Clientset) func(namespace string, selector labels.Selector) cache.SharedInformer {
+	return func(namespace string, selector labels.Selector) cache.SharedInformer {
+		return cache.NewSharedInformer(&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = selector.String()
+				return fakeKubeClient.CoreV1().Secrets(namespace).List(ctx, options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = selector.String()
+				return fakeKubeClient.CoreV1().Secrets(namespace).Watch(ctx, options)
+			},
+		},
+			&corev1.Secret{},
+			0,
+		)
+	}
+}
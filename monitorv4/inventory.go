@@ -0,0 +1,90 @@
+package secret
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Registration describes a secret registered for a parent. It holds no references
+// into the Manager, so it is safe to keep, modify or serialize.
+type Registration struct {
+	// Parent is the key of the parent referencing the secret.
+	Parent ParentKey
+	// Secret is the key of the referenced secret.
+	Secret ObjectKey
+	// RegisteredAt is when the Manager started serving the secret to the parent.
+	RegisteredAt time.Time
+	// Synced is true if the parent's handler was notified about the initial state of the secret.
+	Synced bool
+	// NumHandlers is the number of handlers registered with the informer watching the secret,
+	// which is shared by all parents referencing the secret.
+	NumHandlers int
+	// NotAfter is when the leaf certificate of the secret expires. Zero if the secret holds no certificate.
+	NotAfter time.Time
+}
+
+// List returns the registrations of all parents, sorted by parent and secret.
+func (m *Manager) List() []Registration {
+	m.lock.RLock()
+	registrations := make([]Registration, 0, len(m.registeredHandlers))
+	handlerRegistrations := make([]SecretEventHandlerRegistration, 0, len(m.registeredHandlers))
+	for parent := range m.registeredHandlers {
+		registrations, handlerRegistrations = m.appendRegistrations(registrations, handlerRegistrations, parent)
+	}
+	m.lock.RUnlock()
+
+	return m.describe(registrations, handlerRegistrations)
+}
+
+// Describe returns the registrations of the parent, sorted by secret.
+// Error if the parent is not registered.
+func (m *Manager) Describe(parent ParentKey) ([]Registration, error) {
+	m.lock.RLock()
+	if _, exists := m.registeredHandlers[parent]; !exists {
+		m.lock.RUnlock()
+		return nil, apierrors.NewInternalError(fmt.Errorf("no handler registered for %v", parent))
+	}
+	registrations, handlerRegistrations := m.appendRegistrations(nil, nil, parent)
+	m.lock.RUnlock()
+
+	return m.describe(registrations, handlerRegistrations), nil
+}
+
+// appendRegistrations appends the registrations of the parent and their handler registrations,
+// in the same order. The lock must be held.
+func (m *Manager) appendRegistrations(registrations []Registration, handlerRegistrations []SecretEventHandlerRegistration, parent ParentKey) ([]Registration, []SecretEventHandlerRegistration) {
+	for secret, handlerRegistration := range m.registeredHandlers[parent] {
+		registrations = append(registrations, Registration{
+			Parent:       parent,
+			Secret:       secret,
+			RegisteredAt: m.registeredAt[handlerRegistration],
+			NotAfter:     m.notAfter(handlerRegistration),
+		})
+		handlerRegistrations = append(handlerRegistrations, handlerRegistration)
+	}
+	return registrations, handlerRegistrations
+}
+
+// describe fills in the state of the handler registrations and their informers, and sorts the registrations.
+// It is called without holding the lock, since the monitor takes its own locks.
+func (m *Manager) describe(registrations []Registration, handlerRegistrations []SecretEventHandlerRegistration) []Registration {
+	for i, handlerRegistration := range handlerRegistrations {
+		registrations[i].Synced = handlerRegistration.HasSynced()
+		if status, err := m.monitor.Status(registrations[i].Secret); err == nil {
+			registrations[i].NumHandlers = status.NumHandlers
+		}
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		if registrations[i].Parent != registrations[j].Parent {
+			return lessParentKey(registrations[i].Parent, registrations[j].Parent)
+		}
+		if registrations[i].Secret.Namespace != registrations[j].Secret.Namespace {
+			return registrations[i].Secret.Namespace < registrations[j].Secret.Namespace
+		}
+		return registrations[i].Secret.Name < registrations[j].Secret.Name
+	})
+	return registrations
+}
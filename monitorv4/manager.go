@@ -3,44 +3,106 @@ package secret
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 )
 
+// routeSecretDataKeys are the data keys of a secret referenced by a route.
+var routeSecretDataKeys = []string{v1.TLSCertKey, v1.TLSPrivateKeyKey}
+
+// Manager monitors the secrets referenced by parent objects, e.g. Routes, Ingresses, Pods
+// or custom resources, and notifies the handler registered for each parent.
 type Manager struct {
 	monitor SecretMonitor
-	// map of handlerRegistrations
-	registeredHandlers map[string]SecretEventHandlerRegistration
+	// handlerRegistrations of each parent, keyed by secret
+	registeredHandlers map[ParentKey]map[ObjectKey]SecretEventHandlerRegistration
+	// pending are the keys of parents being registered, whose secret informers are still syncing
+	pending map[ParentKey]struct{}
+	// parents is the reverse index of registeredHandlers, from each secret to the parents referencing it
+	parents map[ObjectKey]map[ParentKey]struct{}
+	// registeredAt is the time each handlerRegistration of registeredHandlers was stored
+	registeredAt map[SecretEventHandlerRegistration]time.Time
 
 	lock sync.RWMutex
 
+	// grants gate the references to secrets in other namespaces. Nil if those are not allowed.
+	grants *referenceGrants
+	// references are the cross-namespace references, keyed by their secret registration
+	references     map[SecretEventHandlerRegistration]*crossNamespaceReference
+	referencesLock sync.RWMutex
+
+	// coalesceWindow is the window within which the events of the secrets of a parent are merged
+	// into a single notification of the parent's handler. Zero disables coalescing.
+	coalesceWindow time.Duration
+	// coalescers are the handlers merging the events, keyed by parent and shared by its secret registrations
+	coalescers     map[ParentKey]*coalescingHandler
+	coalescersLock sync.Mutex
+
+	// requeueExpiring enables the requeues of the parents of expiring certificates
+	requeueExpiring bool
+	// expiryThresholds are the times before the expiry of a certificate at which its parents are requeued
+	expiryThresholds []time.Duration
+	// expiries track the certificate expiry of each secret registration
+	expiries     map[SecretEventHandlerRegistration]*expiryTracker
+	expiriesLock sync.RWMutex
+
+	// enqueuers are the registrations of the built-in handler, shared by the parents of each secret
+	enqueuers map[ObjectKey]*sharedEnqueuer
+	// enqueued are the parents' registrations using the built-in handler of their secret
+	enqueued      map[SecretEventHandlerRegistration]struct{}
+	enqueuersLock sync.Mutex
+
 	// monitors are the producer of the resourceChanges queue
 	resourceChanges workqueue.RateLimitingInterface
+}
+
+func NewManager(kubeClient kubernetes.Interface, queue workqueue.RateLimitingInterface) *Manager {
+	return newManager(NewSecretMonitor(kubeClient), queue)
+}
 
-	// secretHandler cache.ResourceEventHandlerFuncs
-	secretHandler cache.ResourceEventHandler
+// NewManagerWithReferenceGrants returns a Manager which also allows parents to reference secrets in
+// other namespaces, as long as a grant (see ReferenceGrantGVR) in the secret's namespace allows it.
+// The grants are watched; once a grant is revoked, the parent's handler is notified as if the secret was deleted.
+func NewManagerWithReferenceGrants(kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, queue workqueue.RateLimitingInterface) *Manager {
+	m := newManager(NewSecretMonitor(kubeClient), queue)
+	m.grants = newReferenceGrants(dynamicClient, ReferenceGrantGVR)
+	return m
 }
 
-func NewManager(kubeClient *kubernetes.Clientset, queue workqueue.RateLimitingInterface) *Manager {
+func newManager(monitor SecretMonitor, queue workqueue.RateLimitingInterface) *Manager {
 	return &Manager{
-		monitor:            NewSecretMonitor(kubeClient),
+		monitor:            monitor,
 		lock:               sync.RWMutex{},
 		resourceChanges:    queue,
-		registeredHandlers: make(map[string]SecretEventHandlerRegistration),
-
-		// default secret handler
-		secretHandler: nil,
+		registeredHandlers: make(map[ParentKey]map[ObjectKey]SecretEventHandlerRegistration),
+		pending:            make(map[ParentKey]struct{}),
+		parents:            make(map[ObjectKey]map[ParentKey]struct{}),
+		registeredAt:       make(map[SecretEventHandlerRegistration]time.Time),
+		references:         make(map[SecretEventHandlerRegistration]*crossNamespaceReference),
+		coalescers:         make(map[ParentKey]*coalescingHandler),
+		expiries:           make(map[SecretEventHandlerRegistration]*expiryTracker),
+		enqueuers:          make(map[ObjectKey]*sharedEnqueuer),
+		enqueued:           make(map[SecretEventHandlerRegistration]struct{}),
 	}
 }
 
-func (m *Manager) WithSecretHandler(handler cache.ResourceEventHandlerFuncs) *Manager {
-	m.secretHandler = handler
+// WithCoalescingWindow makes the Manager merge bursts of events of the secrets of a parent, e.g. a
+// certificate rotation updating the secret several times within a second, or the certificate and CA
+// bundle rotated together, into a single notification of the parent's handler, delivered once window
+// has passed since the first event of the burst. It carries the final state of the secret which changed last.
+// It must be called before any parent is registered.
+func (m *Manager) WithCoalescingWindow(window time.Duration) *Manager {
+	m.coalesceWindow = window
 	return m
 }
 
@@ -48,71 +110,480 @@ func (m *Manager) Queue() workqueue.RateLimitingInterface {
 	return m.resourceChanges
 }
 
-func (m *Manager) RegisterRoute(ctx context.Context, namespace, routeName, secretName string) error {
+// RegisterParent starts monitoring the secrets in the parent's namespace referenced by the parent,
+// and notifies handler about their events. If the parent is already registered, only the secrets which
+// are new to the set are watched, and only the secrets which are no longer in the set are dropped;
+// the watches of the unchanged secrets are kept. An empty set unregisters the parent.
+// It is safe to call from concurrent workers; the lock is not held while waiting for the secret informers
+// to sync, so registrations of different parents don't block each other.
+func (m *Manager) RegisterParent(ctx context.Context, parent ParentKey, secretNames sets.String, handler cache.ResourceEventHandler) error {
+	return m.registerSecrets(ctx, parent, secretKeys(parent.Namespace, secretNames), nil, handler, false)
+}
+
+// RegisterParentReferences is like RegisterParent, but the secrets may live in other namespaces.
+// A reference to another namespace requires a Manager created by NewManagerWithReferenceGrants,
+// and a grant in that namespace allowing it; a Forbidden error is returned otherwise.
+func (m *Manager) RegisterParentReferences(ctx context.Context, parent ParentKey, secrets []ObjectKey, handler cache.ResourceEventHandler) error {
+	keys := make(map[ObjectKey]struct{}, len(secrets))
+	for _, secret := range secrets {
+		keys[secret] = struct{}{}
+	}
+	return m.registerSecrets(ctx, parent, keys, nil, handler, false)
+}
+
+// UnregisterParent stops monitoring the secrets referenced by the parent.
+func (m *Manager) UnregisterParent(parent ParentKey) error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
+	if _, exists := m.pending[parent]; exists {
+		return apierrors.NewInternalError(fmt.Errorf("registration in progress for %v", parent))
+	}
+
+	// grab registered handlers
+	registrations, exists := m.registeredHandlers[parent]
+	if !exists {
+		return apierrors.NewInternalError(fmt.Errorf("no handler registered for %v", parent))
+	}
+
+	klog.Info("trying to remove handlers of ", parent)
+	for secret, handlerRegistration := range registrations {
+		if err := m.removeHandler(parent, handlerRegistration); err != nil {
+			return apierrors.NewInternalError(err)
+		}
+		delete(registrations, secret)
+		delete(m.registeredAt, handlerRegistration)
+		m.unindexParent(parent, secret)
+	}
+
+	// delete registered handlers from the map
+	delete(m.registeredHandlers, parent)
+	klog.Info("secret manager unregistered ", parent)
+
+	return nil
+}
+
+// GetParentSecret returns the secret with the given name in the parent's namespace referenced by the parent.
+func (m *Manager) GetParentSecret(parent ParentKey, secretName string) (*v1.Secret, error) {
+	return m.GetReferencedSecret(parent, NewObjectKey(parent.Namespace, secretName))
+}
+
+// GetReferencedSecret returns the secret referenced by the parent, which may live in another namespace.
+// A Forbidden error is returned while no grant allows a cross-namespace reference.
+// The informer sync is awaited without holding the lock, so that registrations are not blocked meanwhile.
+func (m *Manager) GetReferencedSecret(parent ParentKey, secret ObjectKey) (*v1.Secret, error) {
+	return m.readSecret(func() (SecretEventHandlerRegistration, error) {
+		handlerRegistration, exists := m.registeredHandlers[parent][secret]
+		if !exists {
+			return nil, apierrors.NewInternalError(fmt.Errorf("no handler registered for %v with secret %v", parent, secret))
+		}
+		return handlerRegistration, nil
+	})
+}
+
+// RegisterRoute starts monitoring the secret referenced by the route, and notifies handler about its events.
+func (m *Manager) RegisterRoute(ctx context.Context, namespace, routeName, secretName string, handler cache.ResourceEventHandler) error {
 	// each route (namespace/routeName) should be registered only once with any secret.
 	// Note: inside a namespace multiple different routes can be registered(watch) with a common secret
-	key := generateKey(namespace, routeName)
-	if _, exists := m.registeredHandlers[key]; exists {
-		return apierrors.NewInternalError(fmt.Errorf("route already registered with key %s", key))
+	return m.registerSecrets(ctx, NewRouteKey(namespace, routeName), secretKeys(namespace, sets.NewString(secretName)), certificateDataKeys(namespace, secretName), handler, true)
+}
+
+// RegisterRouteSecrets starts monitoring all secrets referenced by the route, e.g. the certificate,
+// CA bundle and destination CA, and notifies handler about their events. Like RegisterParent,
+// an already registered route is updated to the new set of secrets.
+// certificateName is the secret of the external certificate among secretNames, of which only tls.crt
+// and tls.key are cached; empty if the route has none. The other secrets are cached in full.
+func (m *Manager) RegisterRouteSecrets(ctx context.Context, namespace, routeName, certificateName string, secretNames sets.String, handler cache.ResourceEventHandler) error {
+	return m.registerSecrets(ctx, NewRouteKey(namespace, routeName), secretKeys(namespace, secretNames), certificateDataKeys(namespace, certificateName), handler, false)
+}
+
+// UpdateReference switches the route to the given secret, e.g. when the route changes its secret reference.
+// The new secret is watched and synced before the watch of the old secret is released, so that there is
+// no window in which GetSecret fails. The old secret stays registered if the new one fails to sync.
+// A route which is not registered yet is registered.
+func (m *Manager) UpdateReference(ctx context.Context, namespace, routeName, secretName string, handler cache.ResourceEventHandler) error {
+	return m.registerSecrets(ctx, NewRouteKey(namespace, routeName), secretKeys(namespace, sets.NewString(secretName)), certificateDataKeys(namespace, secretName), handler, false)
+}
+
+func (m *Manager) UnregisterRoute(namespace, routeName string) error {
+	return m.UnregisterParent(NewRouteKey(namespace, routeName))
+}
+
+// GetSecret returns the secret of a route registered with a single secret.
+// Use GetRouteSecret for routes referencing several secrets.
+// Like GetReferencedSecret, it does not hold the lock while waiting for the informer to sync.
+func (m *Manager) GetSecret(namespace, routeName string) (*v1.Secret, error) {
+	key := NewRouteKey(namespace, routeName)
+
+	return m.readSecret(func() (SecretEventHandlerRegistration, error) {
+		registrations, exists := m.registeredHandlers[key]
+		if !exists {
+			return nil, apierrors.NewInternalError(fmt.Errorf("no handler registered for %v", key))
+		}
+		if len(registrations) != 1 {
+			return nil, apierrors.NewInternalError(fmt.Errorf("%v references %d secrets", key, len(registrations)))
+		}
+		for _, handlerRegistration := range registrations {
+			return handlerRegistration, nil
+		}
+		return nil, nil
+	})
+}
+
+// readSecret reads the secret of the registration returned by lookup, which is called with the lock held.
+// The secret is read after releasing the lock. If that fails because the registration was replaced
+// meanwhile, e.g. by UpdateReference, the secret of the new registration is read instead.
+func (m *Manager) readSecret(lookup func() (SecretEventHandlerRegistration, error)) (*v1.Secret, error) {
+	m.lock.RLock()
+	handlerRegistration, err := lookup()
+	m.lock.RUnlock()
+	for err == nil {
+		var secret *v1.Secret
+		if secret, err = m.getSecret(handlerRegistration); err == nil {
+			return secret, nil
+		}
+
+		m.lock.RLock()
+		current, lookupErr := lookup()
+		m.lock.RUnlock()
+		if lookupErr != nil || current == handlerRegistration {
+			break
+		}
+		handlerRegistration, err = current, nil
 	}
+	return nil, err
+}
+
+// GetRouteSecret returns the secret with the given name referenced by the route.
+func (m *Manager) GetRouteSecret(namespace, routeName, secretName string) (*v1.Secret, error) {
+	return m.GetParentSecret(NewRouteKey(namespace, routeName), secretName)
+}
 
-	handlerRegistration, err := m.monitor.AddSecretEventHandler(ctx, namespace, secretName, m.secretHandler)
+// secretKeys returns the keys of the secrets with the given names in the namespace.
+func secretKeys(namespace string, secretNames sets.String) map[ObjectKey]struct{} {
+	keys := make(map[ObjectKey]struct{}, secretNames.Len())
+	for secretName := range secretNames {
+		keys[NewObjectKey(namespace, secretName)] = struct{}{}
+	}
+	return keys
+}
+
+// certificateDataKeys returns the data keys to cache of the external certificate secret of a route, if any.
+func certificateDataKeys(namespace, certificateName string) map[ObjectKey][]string {
+	if certificateName == "" {
+		return nil
+	}
+	return map[ObjectKey][]string{NewObjectKey(namespace, certificateName): routeSecretDataKeys}
+}
+
+// registerSecrets updates the secrets registered for the parent to secrets.
+// Only the data keys listed in dataKeys are cached for a secret, or all of them if it isn't listed.
+// If exclusive is set, the parent must not be registered yet.
+func (m *Manager) registerSecrets(ctx context.Context, parent ParentKey, secrets map[ObjectKey]struct{}, dataKeys map[ObjectKey][]string, handler cache.ResourceEventHandler, exclusive bool) error {
+	current, err := m.reserve(parent, secrets, exclusive)
 	if err != nil {
-		return apierrors.NewInternalError(err)
+		return err
+	}
+
+	// watch the new secrets first, so that a failure leaves the current registrations untouched
+	added := map[ObjectKey]SecretEventHandlerRegistration{}
+	for _, secret := range sortedKeys(secrets) {
+		if _, exists := current[secret]; exists {
+			continue
+		}
+		handlerRegistration, err := m.addParentEventHandler(ctx, parent, secret, dataKeys[secret], handler)
+		if err != nil {
+			m.removeHandlers(parent, added)
+			m.release(parent, current)
+			// keep sync failures and missing grants distinguishable for the caller
+			if ReasonForSyncError(err) != "" || apierrors.IsForbidden(err) {
+				return err
+			}
+			return apierrors.NewInternalError(err)
+		}
+		added[secret] = handlerRegistration
 	}
-	m.registeredHandlers[key] = handlerRegistration
-	klog.Info(fmt.Sprintf("secret manager registered route for key %s with secret %s", key, secretName))
+
+	registrations := make(map[ObjectKey]SecretEventHandlerRegistration, len(secrets))
+	removed := map[ObjectKey]SecretEventHandlerRegistration{}
+	for secret, handlerRegistration := range current {
+		if _, exists := secrets[secret]; exists {
+			registrations[secret] = handlerRegistration
+		} else {
+			removed[secret] = handlerRegistration
+		}
+	}
+	for secret, handlerRegistration := range added {
+		registrations[secret] = handlerRegistration
+	}
+	m.release(parent, registrations)
+
+	// drop the secrets which are no longer referenced, once readers see the new registrations
+	m.removeHandlers(parent, removed)
+	klog.Info(fmt.Sprintf("secret manager registered %v with secrets %v", parent, sortedKeys(secrets)))
 
 	return nil
 }
 
-func (m *Manager) UnregisterRoute(namespace, routeName string) error {
-	m.lock.Lock()
-	defer m.lock.Unlock()
+// sortedKeys returns the secret keys sorted by namespace and name.
+func sortedKeys(secrets map[ObjectKey]struct{}) []ObjectKey {
+	keys := make([]ObjectKey, 0, len(secrets))
+	for secret := range secrets {
+		keys = append(keys, secret)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Namespace != keys[j].Namespace {
+			return keys[i].Namespace < keys[j].Namespace
+		}
+		return keys[i].Name < keys[j].Name
+	})
+	return keys
+}
 
-	key := generateKey(namespace, routeName)
+// addParentEventHandler adds the handler for the secret referenced by the parent, merging bursts of
+// events if a coalescing window is configured, and tracking the expiry of the secret's certificate.
+func (m *Manager) addParentEventHandler(ctx context.Context, parent ParentKey, secret ObjectKey, dataKeys []string, handler cache.ResourceEventHandler) (SecretEventHandlerRegistration, error) {
+	// the built-in handler enqueues only the parent of its registration, and is shared by all parents
+	// of a secret in their namespace instead, being added once per secret before the parent's own registration,
+	// which then only tracks the expiry. The shared handler coalesces the events of the secret itself.
+	shared := false
+	if enqueuer, ok := handler.(parentEnqueuer); ok {
+		enqueuer.parent = &parent
+		handler = enqueuer
+		shared = secret.Namespace == parent.Namespace
+	}
 
-	// grab registered handler
-	handlerRegistration, exists := m.registeredHandlers[key]
-	if !exists {
-		return apierrors.NewInternalError(fmt.Errorf("no handler registered with key %s", key))
+	switch {
+	case shared:
+		if err := m.acquireEnqueuer(ctx, parent, secret); err != nil {
+			return nil, err
+		}
+		handler = cache.ResourceEventHandlerFuncs{}
+	case m.coalesceWindow > 0:
+		handler = m.acquireCoalescer(parent, handler)
 	}
+	// the tracker sees every event, so that the expiry is never behind the cache
+	tracker := newExpiryTracker(m, parent, handler)
 
-	klog.Info("trying to remove handler with key", key)
-	err := m.monitor.RemoveSecretEventHandler(handlerRegistration)
+	handlerRegistration, err := m.addSecretEventHandler(ctx, parent, secret, dataKeys, tracker)
 	if err != nil {
-		return apierrors.NewInternalError(err)
+		tracker.stop()
+		switch {
+		case shared:
+			m.releaseEnqueuer(secret)
+		case m.coalesceWindow > 0:
+			m.releaseCoalescer(parent, secret)
+		}
+		return nil, err
 	}
 
-	// delete registered handler from the map
-	delete(m.registeredHandlers, key)
-	klog.Info("secret manager unregistered route ", key)
+	m.expiriesLock.Lock()
+	m.expiries[handlerRegistration] = tracker
+	m.expiriesLock.Unlock()
 
-	return nil
+	if shared {
+		m.enqueuersLock.Lock()
+		m.enqueued[handlerRegistration] = struct{}{}
+		m.enqueuersLock.Unlock()
+	}
+
+	return handlerRegistration, nil
 }
 
-func (m *Manager) GetSecret(namespace, routeName string) (*v1.Secret, error) {
-	m.lock.Lock()
-	defer m.lock.Unlock()
+// acquireCoalescer returns the coalescer of the parent, which is shared by all its secret registrations,
+// creating it for the first one. The coalescer notifies handler from now on, also about the events
+// of the secrets registered earlier.
+func (m *Manager) acquireCoalescer(parent ParentKey, handler cache.ResourceEventHandler) *coalescingHandler {
+	m.coalescersLock.Lock()
+	defer m.coalescersLock.Unlock()
+
+	coalescer, exists := m.coalescers[parent]
+	if !exists {
+		coalescer = newCoalescingHandler(handler, m.coalesceWindow)
+		m.coalescers[parent] = coalescer
+	} else {
+		coalescer.setHandler(handler)
+	}
+	coalescer.refs++
+	return coalescer
+}
 
-	key := generateKey(namespace, routeName)
+// releaseCoalescer drops the events of the secret waiting for the coalescing window to pass,
+// and stops the coalescer of the parent once its last secret registration is released.
+func (m *Manager) releaseCoalescer(parent ParentKey, secret ObjectKey) {
+	m.coalescersLock.Lock()
+	defer m.coalescersLock.Unlock()
 
-	handlerRegistration, exists := m.registeredHandlers[key]
+	coalescer, exists := m.coalescers[parent]
 	if !exists {
-		return nil, apierrors.NewInternalError(fmt.Errorf("no handler registered with key %s", key))
+		return
+	}
+	coalescer.drop(secret)
+	if coalescer.refs--; coalescer.refs <= 0 {
+		coalescer.stop()
+		delete(m.coalescers, parent)
+	}
+}
+
+// addSecretEventHandler adds the handler for the secret referenced by the parent, caching only dataKeys
+// if not nil. A secret in another namespace is only watched if a grant allows the reference.
+func (m *Manager) addSecretEventHandler(ctx context.Context, parent ParentKey, secret ObjectKey, dataKeys []string, handler cache.ResourceEventHandler) (SecretEventHandlerRegistration, error) {
+	if secret.Namespace == parent.Namespace {
+		return m.addMonitorEventHandler(ctx, secret, dataKeys, handler)
+	}
+
+	if m.grants == nil {
+		return nil, apierrors.NewForbidden(v1.Resource("secrets"), secret.Name, fmt.Errorf("cross-namespace secret references are not enabled"))
+	}
+	reference := &crossNamespaceReference{
+		parent:    parent,
+		secret:    secret,
+		handler:   handler,
+		grants:    m.grants,
+		getSecret: m.monitor.GetSecret,
+	}
+	allowed, err := reference.watchGrants(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		reference.stopWatchingGrants()
+		return nil, reference.forbidden()
 	}
 
-	obj, err := m.monitor.GetSecret(handlerRegistration)
+	handlerRegistration, err := m.addMonitorEventHandler(ctx, secret, dataKeys, reference)
 	if err != nil {
+		reference.stopWatchingGrants()
 		return nil, err
 	}
+	reference.lock.Lock()
+	reference.secretRegistration = handlerRegistration
+	reference.lock.Unlock()
+
+	m.referencesLock.Lock()
+	m.references[handlerRegistration] = reference
+	m.referencesLock.Unlock()
+
+	return handlerRegistration, nil
+}
 
-	return obj, nil
+// addMonitorEventHandler adds the handler for the secret to the monitor, caching only dataKeys if not nil.
+func (m *Manager) addMonitorEventHandler(ctx context.Context, secret ObjectKey, dataKeys []string, handler cache.ResourceEventHandler) (SecretEventHandlerRegistration, error) {
+	if dataKeys == nil {
+		return m.monitor.AddSecretEventHandler(ctx, secret.Namespace, secret.Name, handler)
+	}
+	return m.monitor.AddSecretDataEventHandler(ctx, secret.Namespace, secret.Name, dataKeys, handler)
 }
 
-func generateKey(namespace, route string) string {
-	return fmt.Sprintf("%s/%s", namespace, route)
+// getSecret returns the secret of the registration, or a Forbidden error if the grant of a
+// cross-namespace reference was revoked.
+func (m *Manager) getSecret(handlerRegistration SecretEventHandlerRegistration) (*v1.Secret, error) {
+	m.referencesLock.RLock()
+	reference, exists := m.references[handlerRegistration]
+	m.referencesLock.RUnlock()
+	if exists && !reference.isGranted() {
+		return nil, reference.forbidden()
+	}
+
+	return m.monitor.GetSecret(handlerRegistration)
+}
+
+// reserve marks the parent as being registered, and returns a copy of its current registrations.
+// The parent is indexed for the secrets up front, so that events of new secrets received while
+// registering already find it. Error if the parent is being registered, or if exclusive is set
+// and the parent is already registered.
+func (m *Manager) reserve(parent ParentKey, secrets map[ObjectKey]struct{}, exclusive bool) (map[ObjectKey]SecretEventHandlerRegistration, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, exists := m.pending[parent]; exists {
+		return nil, apierrors.NewInternalError(fmt.Errorf("registration in progress for %v", parent))
+	}
+	current, exists := m.registeredHandlers[parent]
+	if exists && exclusive {
+		return nil, apierrors.NewInternalError(fmt.Errorf("already registered %v", parent))
+	}
+	m.pending[parent] = struct{}{}
+	for secret := range secrets {
+		m.indexParent(parent, secret)
+	}
+
+	registrations := make(map[ObjectKey]SecretEventHandlerRegistration, len(current))
+	for secret, handlerRegistration := range current {
+		registrations[secret] = handlerRegistration
+	}
+	return registrations, nil
+}
+
+// release stores the registrations of the parent, and clears its reservation.
+func (m *Manager) release(parent ParentKey, registrations map[ObjectKey]SecretEventHandlerRegistration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.pending, parent)
+	m.reindexParent(parent, registrations)
+
+	// keep the registration time of the kept registrations
+	now := time.Now()
+	for secret, handlerRegistration := range m.registeredHandlers[parent] {
+		if registrations[secret] != handlerRegistration {
+			delete(m.registeredAt, handlerRegistration)
+		}
+	}
+	for _, handlerRegistration := range registrations {
+		if _, exists := m.registeredAt[handlerRegistration]; !exists {
+			m.registeredAt[handlerRegistration] = now
+		}
+	}
+	if len(registrations) == 0 {
+		delete(m.registeredHandlers, parent)
+		return
+	}
+	m.registeredHandlers[parent] = registrations
+}
+
+// removeHandlers removes the handlers of the registrations of the parent, logging failures.
+func (m *Manager) removeHandlers(parent ParentKey, registrations map[ObjectKey]SecretEventHandlerRegistration) {
+	for secret, handlerRegistration := range registrations {
+		if err := m.removeHandler(parent, handlerRegistration); err != nil {
+			klog.Error("failed to remove handler for secret ", secret, ": ", err)
+		}
+	}
+}
+
+// removeHandler removes the handler of the registration of the parent, stops watching the grants of a
+// cross-namespace reference, stops coalescing its events, and forgets the expiry of its certificate.
+func (m *Manager) removeHandler(parent ParentKey, handlerRegistration SecretEventHandlerRegistration) error {
+	if err := m.monitor.RemoveSecretEventHandler(handlerRegistration); err != nil {
+		return err
+	}
+
+	m.referencesLock.Lock()
+	reference, exists := m.references[handlerRegistration]
+	delete(m.references, handlerRegistration)
+	m.referencesLock.Unlock()
+	if exists {
+		reference.stopWatchingGrants()
+	}
+
+	m.expiriesLock.Lock()
+	tracker, exists := m.expiries[handlerRegistration]
+	delete(m.expiries, handlerRegistration)
+	m.expiriesLock.Unlock()
+	if exists {
+		tracker.stop()
+	}
+
+	m.enqueuersLock.Lock()
+	_, enqueued := m.enqueued[handlerRegistration]
+	delete(m.enqueued, handlerRegistration)
+	m.enqueuersLock.Unlock()
+	switch {
+	case enqueued:
+		m.releaseEnqueuer(handlerRegistration.GetKey())
+	case m.coalesceWindow > 0:
+		// drop the events still waiting for the coalescing window to pass
+		m.releaseCoalescer(parent, handlerRegistration.GetKey())
+	}
+	return nil
 }
@@ -0,0 +1,665 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// fakeManager returns a Manager whose secret informers list/watch fakeKubeClient.
+func fakeManager(fakeKubeClient *fake.Clientset) *Manager {
+	sm := fakeSecretMonitor(fakeKubeClient)
+	return newManager(sm, workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()))
+}
+
+// countingQueue counts the items added to the queue.
+type countingQueue struct {
+	workqueue.RateLimitingInterface
+	adds atomic.Int32
+}
+
+func (q *countingQueue) Add(item interface{}) {
+	q.adds.Add(1)
+	q.RateLimitingInterface.Add(item)
+}
+
+// registeredSecretNames returns the names of the secrets registered for the parent.
+func registeredSecretNames(m *Manager, parent ParentKey) sets.String {
+	names := sets.NewString()
+	for secret := range m.registeredHandlers[parent] {
+		names.Insert(secret.Name)
+	}
+	return names
+}
+
+func TestManagerConcurrentRegisterRoute(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		numRoutes = 20
+	)
+
+	// routes share the secrets; the fake client ignores field selectors,
+	// so each secret is put in its own namespace
+	secrets := []runtime.Object{}
+	for i := 0; i < 3; i++ {
+		secrets = append(secrets, fakeSecret(fmt.Sprintf("%s-%d", namespace, i), "secret"))
+	}
+	fakeKubeClient := fake.NewSimpleClientset(secrets...)
+	m := fakeManager(fakeKubeClient)
+
+	// each route counts the add events its own handler received
+	numAdds := make([]atomic.Int32, numRoutes)
+	var wg sync.WaitGroup
+	for i := 0; i < numRoutes; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler := cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) { numAdds[i].Add(1) },
+			}
+			if err := m.RegisterRoute(context.TODO(), fmt.Sprintf("%s-%d", namespace, i%3), fmt.Sprintf("route-%d", i), "secret", handler); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < numRoutes; i++ {
+		if n := numAdds[i].Load(); n != 1 {
+			t.Errorf("expected route-%d handler to be notified once, got %d", i, n)
+		}
+		if _, err := m.GetSecret(fmt.Sprintf("%s-%d", namespace, i%3), fmt.Sprintf("route-%d", i)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if err := m.RegisterRoute(context.TODO(), namespace+"-0", "route-0", "secret", cache.ResourceEventHandlerFuncs{}); err == nil {
+		t.Error("expecting an error for duplicate registration, got nil")
+	}
+
+	for i := 0; i < numRoutes; i++ {
+		if err := m.UnregisterRoute(fmt.Sprintf("%s-%d", namespace, i%3), fmt.Sprintf("route-%d", i)); err != nil {
+			t.Error(err)
+		}
+	}
+	if len(m.registeredHandlers) != 0 || len(m.pending) != 0 {
+		t.Errorf("expected no registrations left, got %v %v", m.registeredHandlers, m.pending)
+	}
+}
+
+func TestManagerGetSecretWithoutLock(t *testing.T) {
+	var (
+		slow = NewObjectKey("slow", "secret")
+		fast = NewObjectKey("fast", "secret")
+	)
+
+	fakeKubeClient := fake.NewSimpleClientset(fakeSecret(slow.Namespace, slow.Name), fakeSecret(fast.Namespace, fast.Name))
+	m := fakeManager(fakeKubeClient)
+	sm := m.monitor.(*secretMonitor)
+	// the lists of the slow secret block once blocked is set, until released
+	var blocked atomic.Bool
+	release := make(chan struct{})
+	sm.createInformer = func(namespace, name string) cache.SharedInformer {
+		return cache.NewSharedInformer(&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if namespace == slow.Namespace && blocked.Load() {
+					<-release
+				}
+				return fakeKubeClient.CoreV1().Secrets(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return fakeKubeClient.CoreV1().Secrets(namespace).Watch(context.TODO(), options)
+			},
+		},
+			&corev1.Secret{},
+			0,
+		)
+	}
+
+	if err := m.RegisterRoute(context.TODO(), slow.Namespace, "route", slow.Name, cache.ResourceEventHandlerFuncs{}); err != nil {
+		t.Fatal(err)
+	}
+	defer close(release)
+
+	// the restarted informer of the slow secret can't sync, so reading the secret waits for it
+	blocked.Store(true)
+	handlerRegistration := m.registeredHandlers[NewRouteKey(slow.Namespace, "route")][slow]
+	go sm.Restart(context.TODO(), handlerRegistration)
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return !handlerRegistration.HasSynced(), nil
+	}); err != nil {
+		t.Fatal("expected the restarted informer to be syncing")
+	}
+	go m.GetSecret(slow.Namespace, "route")
+
+	// a secret read waiting for the sync does not block the registration of other routes
+	registered := make(chan error, 1)
+	go func() {
+		registered <- m.RegisterRoute(context.TODO(), fast.Namespace, "route", fast.Name, cache.ResourceEventHandlerFuncs{})
+	}()
+	select {
+	case err := <-registered:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("registration blocked by a secret read waiting for the sync")
+	}
+}
+
+func TestManagerRegisterRouteSecrets(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		routeName = "route"
+	)
+
+	scenarios := []struct {
+		name          string
+		initial       sets.String
+		updated       sets.String
+		expectKept    sets.String
+		expectSecrets sets.String
+	}{
+		{
+			name:          "unchanged secrets keep their watch",
+			initial:       sets.NewString("tls", "ca"),
+			updated:       sets.NewString("tls", "ca"),
+			expectKept:    sets.NewString("tls", "ca"),
+			expectSecrets: sets.NewString("tls", "ca"),
+		},
+		{
+			name:          "new secret is added and removed secret is dropped",
+			initial:       sets.NewString("tls", "ca"),
+			updated:       sets.NewString("tls", "destination-ca"),
+			expectKept:    sets.NewString("tls"),
+			expectSecrets: sets.NewString("tls", "destination-ca"),
+		},
+		{
+			name:          "empty set unregisters the route",
+			initial:       sets.NewString("tls", "ca"),
+			updated:       sets.NewString(),
+			expectKept:    sets.NewString(),
+			expectSecrets: sets.NewString(),
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			fakeKubeClient := fake.NewSimpleClientset(
+				fakeSecret(namespace, "tls"),
+				fakeSecret(namespace, "ca"),
+				fakeSecret(namespace, "destination-ca"),
+			)
+			m := fakeManager(fakeKubeClient)
+			key := NewRouteKey(namespace, routeName)
+
+			if err := m.RegisterRouteSecrets(context.TODO(), namespace, routeName, "", s.initial, cache.ResourceEventHandlerFuncs{}); err != nil {
+				t.Fatal(err)
+			}
+			initial := map[ObjectKey]SecretEventHandlerRegistration{}
+			for secret, r := range m.registeredHandlers[key] {
+				initial[secret] = r
+			}
+
+			if err := m.RegisterRouteSecrets(context.TODO(), namespace, routeName, "", s.updated, cache.ResourceEventHandlerFuncs{}); err != nil {
+				t.Fatal(err)
+			}
+
+			got := registeredSecretNames(m, key)
+			if !got.Equal(s.expectSecrets) {
+				t.Errorf("expected secrets %v, got %v", s.expectSecrets.List(), got.List())
+			}
+			for secret, r := range m.registeredHandlers[key] {
+				if kept := initial[secret] == r; kept != s.expectKept.Has(secret.Name) {
+					t.Errorf("expected watch of secret %s to be kept: %t", secret.Name, s.expectKept.Has(secret.Name))
+				}
+				if _, err := m.GetRouteSecret(namespace, routeName, secret.Name); err != nil {
+					t.Error(err)
+				}
+			}
+			if s.expectSecrets.Len() == 0 {
+				if _, exists := m.registeredHandlers[key]; exists {
+					t.Error("route should be unregistered")
+				}
+			} else if err := m.UnregisterRoute(namespace, routeName); err != nil {
+				t.Error(err)
+			}
+			if len(m.monitor.(*secretMonitor).monitors) != 0 {
+				t.Error("all secret informers should be stopped")
+			}
+		})
+	}
+}
+
+func TestManagerRegisterRouteSecretsDataKeys(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		routeName = "route"
+	)
+
+	certificate := fakeSecret(namespace, "tls")
+	certificate.Data = map[string][]byte{
+		corev1.TLSCertKey:       []byte("cert"),
+		corev1.TLSPrivateKeyKey: []byte("key"),
+		"extra":                 []byte("extra"),
+	}
+	caBundle := fakeSecret(namespace, "ca")
+	caBundle.Data = map[string][]byte{
+		"ca.crt": []byte("ca"),
+	}
+	fakeKubeClient := fake.NewSimpleClientset(certificate, caBundle)
+	m := fakeManager(fakeKubeClient)
+
+	if err := m.RegisterRouteSecrets(context.TODO(), namespace, routeName, "tls", sets.NewString("tls", "ca"), cache.ResourceEventHandlerFuncs{}); err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []struct {
+		name       string
+		secretName string
+		expectKeys sets.String
+	}{
+		{
+			name:       "certificate secret keeps only tls.crt and tls.key",
+			secretName: "tls",
+			expectKeys: sets.NewString(corev1.TLSCertKey, corev1.TLSPrivateKeyKey),
+		},
+		{
+			name:       "other secrets are cached in full",
+			secretName: "ca",
+			expectKeys: sets.NewString("ca.crt"),
+		},
+	}
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.name, func(t *testing.T) {
+			secret, err := m.GetRouteSecret(namespace, routeName, s.secretName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := sets.NewString()
+			for dataKey := range secret.Data {
+				got.Insert(dataKey)
+			}
+			if !got.Equal(s.expectKeys) {
+				t.Errorf("expected data keys %v, got %v", s.expectKeys.List(), got.List())
+			}
+		})
+	}
+}
+
+func TestManagerUpdateReference(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		routeName = "route"
+	)
+
+	scenarios := []struct {
+		name         string
+		newSecret    string
+		expectErr    bool
+		expectSecret string
+	}{
+		{
+			name:         "route switches to the new secret",
+			newSecret:    "new",
+			expectSecret: "new",
+		},
+		{
+			name:         "route keeps the old secret if the new one fails to sync",
+			newSecret:    "denied",
+			expectErr:    true,
+			expectSecret: "old",
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			fakeKubeClient := fake.NewSimpleClientset(fakeSecret(namespace, "old"), fakeSecret(namespace, "new"))
+			fakeKubeClient.PrependReactor("list", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				if action.(clienttesting.ListAction).GetListRestrictions().Fields.Matches(fields.Set{"metadata.name": "denied"}) {
+					return true, nil, apierrors.NewForbidden(corev1.Resource("secrets"), "denied", fmt.Errorf("denied"))
+				}
+				return false, nil, nil
+			})
+			m := fakeManager(fakeKubeClient)
+
+			if err := m.RegisterRoute(context.TODO(), namespace, routeName, "old", cache.ResourceEventHandlerFuncs{}); err != nil {
+				t.Fatal(err)
+			}
+
+			// the route has a synced secret during the whole update
+			done := make(chan struct{})
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-done:
+						return
+					default:
+					}
+					if _, err := m.GetSecret(namespace, routeName); err != nil {
+						t.Errorf("secret not available during update: %v", err)
+						return
+					}
+				}
+			}()
+
+			err := m.UpdateReference(context.TODO(), namespace, routeName, s.newSecret, cache.ResourceEventHandlerFuncs{})
+			close(done)
+			wg.Wait()
+			if (err != nil) != s.expectErr {
+				t.Fatalf("expected error %t, got %v", s.expectErr, err)
+			}
+
+			got := registeredSecretNames(m, NewRouteKey(namespace, routeName))
+			if !got.Equal(sets.NewString(s.expectSecret)) {
+				t.Errorf("expected secret %s, got %v", s.expectSecret, got.List())
+			}
+			if _, exists := m.monitor.(*secretMonitor).monitors[NewObjectKey(namespace, s.expectSecret)]; !exists {
+				t.Errorf("expected secret %s to be watched", s.expectSecret)
+			}
+			if n := len(m.monitor.(*secretMonitor).monitors); n != 1 {
+				t.Errorf("expected 1 secret informer, got %d", n)
+			}
+		})
+	}
+}
+
+func TestManagerRegisterParent(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		name      = "frontend"
+		meta      = &metav1.ObjectMeta{Namespace: namespace, Name: name, UID: "uid-1"}
+		route     = NewParentKey(routeGroupKind, meta)
+		ingress   = NewParentKey(schema.GroupKind{Group: "networking.k8s.io", Kind: "Ingress"}, meta)
+		recreated = NewParentKey(routeGroupKind, &metav1.ObjectMeta{Namespace: namespace, Name: name, UID: "uid-2"})
+	)
+
+	fakeKubeClient := fake.NewSimpleClientset(fakeSecret(namespace, "tls"), fakeSecret(namespace, "ca"))
+	m := fakeManager(fakeKubeClient)
+
+	// parents with the same namespace and name don't collide
+	for parent, secretName := range map[ParentKey]string{route: "tls", ingress: "ca", recreated: "tls"} {
+		if err := m.RegisterParent(context.TODO(), parent, sets.NewString(secretName), cache.ResourceEventHandlerFuncs{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(m.registeredHandlers); n != 3 {
+		t.Errorf("expected 3 registered parents, got %d", n)
+	}
+	if _, err := m.GetParentSecret(ingress, "ca"); err != nil {
+		t.Error(err)
+	}
+	if _, err := m.GetParentSecret(ingress, "tls"); err == nil {
+		t.Error("expecting an error for a secret referenced by another parent, got nil")
+	}
+
+	if err := m.UnregisterParent(route); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetParentSecret(recreated, "tls"); err != nil {
+		t.Errorf("secret of recreated parent should still be monitored: %v", err)
+	}
+	if err := m.UnregisterParent(route); err == nil {
+		t.Error("expecting an error for unregistered parent, got nil")
+	}
+	for _, parent := range []ParentKey{ingress, recreated} {
+		if err := m.UnregisterParent(parent); err != nil {
+			t.Error(err)
+		}
+	}
+	if len(m.monitor.(*secretMonitor).monitors) != 0 {
+		t.Error("all secret informers should be stopped")
+	}
+}
+
+func TestManagerParentsFor(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		frontend  = NewRouteKey(namespace, "frontend")
+		backend   = NewRouteKey(namespace, "backend")
+		shared    = NewObjectKey(namespace, "shared")
+		ca        = NewObjectKey(namespace, "ca")
+	)
+
+	fakeKubeClient := fake.NewSimpleClientset(fakeSecret(namespace, "shared"), fakeSecret(namespace, "ca"))
+	m := fakeManager(fakeKubeClient)
+	queue := &countingQueue{RateLimitingInterface: m.resourceChanges}
+	m.resourceChanges = queue
+	handler := m.EnqueueParentsHandler()
+
+	if err := m.RegisterRouteSecrets(context.TODO(), namespace, "frontend", "", sets.NewString("shared", "ca"), handler); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RegisterRouteSecrets(context.TODO(), namespace, "backend", "", sets.NewString("shared"), handler); err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []struct {
+		name          string
+		secret        ObjectKey
+		expectParents []ParentKey
+	}{
+		{
+			name:          "shared secret is referenced by both routes",
+			secret:        shared,
+			expectParents: []ParentKey{backend, frontend},
+		},
+		{
+			name:          "secret is referenced by a single route",
+			secret:        ca,
+			expectParents: []ParentKey{frontend},
+		},
+		{
+			name:          "unreferenced secret has no parents",
+			secret:        NewObjectKey(namespace, "other"),
+			expectParents: []ParentKey{},
+		},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if got := m.ParentsFor(s.secret); !reflect.DeepEqual(got, s.expectParents) {
+				t.Errorf("expected parents %v, got %v", s.expectParents, got)
+			}
+		})
+	}
+
+	// drain the initial adds, which enqueued the routes already
+	for m.Queue().Len() > 0 {
+		item, _ := m.Queue().Get()
+		m.Queue().Done(item)
+	}
+	handler.OnUpdate(fakeSecret(namespace, "shared"), fakeSecret(namespace, "shared"))
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: namespace + "/shared", Obj: fakeSecret(namespace, "shared")})
+	if n := m.Queue().Len(); n != 2 {
+		t.Errorf("expected both routes to be enqueued once, got %d items", n)
+	}
+	for m.Queue().Len() > 0 {
+		item, _ := m.Queue().Get()
+		m.Queue().Done(item)
+	}
+
+	// the handler is added once for the shared secret, which enqueues each route once per event
+	if len(m.enqueuers) != 2 {
+		t.Errorf("expected a handler per secret, got %d", len(m.enqueuers))
+	}
+	// besides the shared handler, the secret only has the registrations of the routes, which leave
+	// the enqueuing to it, so that no further adds follow the ones awaited below
+	if len(m.enqueued) != 3 {
+		t.Errorf("expected the registrations of the routes to use the shared handlers, got %d", len(m.enqueued))
+	}
+	if status, err := m.monitor.Status(shared); err != nil || status.NumHandlers != 3 {
+		t.Errorf("expected the shared handler and a registration per route, got %+v %v", status, err)
+	}
+	queue.adds.Store(0)
+	updated := fakeSecret(namespace, "shared")
+	updated.Data = map[string][]byte{"test": {5}}
+	if _, err := fakeKubeClient.CoreV1().Secrets(namespace).Update(context.TODO(), updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return queue.adds.Load() >= 2, nil
+	}); err != nil {
+		t.Fatal("expected both routes to be enqueued")
+	}
+	if n := queue.adds.Load(); n != 2 {
+		t.Errorf("expected 2 adds, got %d", n)
+	}
+
+	// dropped secrets and unregistered parents leave the index
+	if err := m.RegisterRouteSecrets(context.TODO(), namespace, "frontend", "", sets.NewString("ca"), handler); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.ParentsFor(shared); !reflect.DeepEqual(got, []ParentKey{backend}) {
+		t.Errorf("expected parents %v, got %v", []ParentKey{backend}, got)
+	}
+	for _, route := range []string{"frontend", "backend"} {
+		if err := m.UnregisterRoute(namespace, route); err != nil {
+			t.Error(err)
+		}
+	}
+	if len(m.parents) != 0 {
+		t.Errorf("expected an empty index, got %v", m.parents)
+	}
+	if len(m.enqueuers) != 0 || len(m.enqueued) != 0 {
+		t.Errorf("expected the shared handlers to be removed, got %v", m.enqueuers)
+	}
+}
+
+func TestManagerEnqueueParentsCoalescing(t *testing.T) {
+	namespace := "sandbox"
+
+	m := fakeManager(fake.NewSimpleClientset(fakeSecret(namespace, "shared"))).WithCoalescingWindow(time.Minute)
+	for _, route := range []string{"frontend", "backend"} {
+		if err := m.RegisterRouteSecrets(context.TODO(), namespace, route, "", sets.NewString("shared"), m.EnqueueParentsHandler()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the shared handler coalesces the events of the secret, so the routes need no coalescer of their own
+	if n := len(m.coalescers); n != 0 {
+		t.Errorf("expected no coalescer per route, got %d", n)
+	}
+	enqueuer, exists := m.enqueuers[NewObjectKey(namespace, "shared")]
+	if !exists || enqueuer.coalescer == nil || enqueuer.refs != 2 {
+		t.Fatalf("expected a coalescing handler shared by both routes, got %+v", enqueuer)
+	}
+
+	for _, route := range []string{"frontend", "backend"} {
+		if err := m.UnregisterRoute(namespace, route); err != nil {
+			t.Error(err)
+		}
+	}
+	if len(m.enqueuers) != 0 || len(m.coalescers) != 0 {
+		t.Errorf("expected the handlers to be removed, got %v and %v", m.enqueuers, m.coalescers)
+	}
+}
+
+func TestManagerList(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		frontend  = NewRouteKey(namespace, "frontend")
+		backend   = NewRouteKey(namespace, "backend")
+	)
+
+	fakeKubeClient := fake.NewSimpleClientset(fakeSecret(namespace, "shared"), fakeSecret(namespace, "ca"))
+	m := fakeManager(fakeKubeClient)
+
+	before := time.Now()
+	if err := m.RegisterRouteSecrets(context.TODO(), namespace, "frontend", "", sets.NewString("shared", "ca"), cache.ResourceEventHandlerFuncs{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RegisterRouteSecrets(context.TODO(), namespace, "backend", "", sets.NewString("shared"), cache.ResourceEventHandlerFuncs{}); err != nil {
+		t.Fatal(err)
+	}
+	// handlers added to a running informer are synced once the replay of its cache is delivered
+	for _, registrations := range m.registeredHandlers {
+		for _, r := range registrations {
+			if !cache.WaitForCacheSync(context.TODO().Done(), r.HasSynced) {
+				t.Fatal("handler failed to sync")
+			}
+		}
+	}
+
+	scenarios := []struct {
+		name   string
+		list   func() ([]Registration, error)
+		expect []Registration
+	}{
+		{
+			name: "list returns the registrations of all parents",
+			list: func() ([]Registration, error) { return m.List(), nil },
+			expect: []Registration{
+				{Parent: backend, Secret: NewObjectKey(namespace, "shared"), Synced: true, NumHandlers: 2},
+				{Parent: frontend, Secret: NewObjectKey(namespace, "ca"), Synced: true, NumHandlers: 1},
+				{Parent: frontend, Secret: NewObjectKey(namespace, "shared"), Synced: true, NumHandlers: 2},
+			},
+		},
+		{
+			name: "describe returns the registrations of the parent",
+			list: func() ([]Registration, error) { return m.Describe(backend) },
+			expect: []Registration{
+				{Parent: backend, Secret: NewObjectKey(namespace, "shared"), Synced: true, NumHandlers: 2},
+			},
+		},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			got, err := s.list()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range got {
+				if got[i].RegisteredAt.Before(before) {
+					t.Errorf("expected registration time after %v, got %v", before, got[i].RegisteredAt)
+				}
+				got[i].RegisteredAt = time.Time{}
+			}
+			if !reflect.DeepEqual(got, s.expect) {
+				t.Errorf("expected registrations %+v, got %+v", s.expect, got)
+			}
+			if _, err := json.Marshal(got); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	// the snapshot is not affected by later changes
+	snapshot := m.List()
+	if err := m.RegisterRouteSecrets(context.TODO(), namespace, "frontend", "", sets.NewString("shared"), cache.ResourceEventHandlerFuncs{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot) != 3 || snapshot[1].Secret.Name != "ca" {
+		t.Errorf("snapshot changed: %+v", snapshot)
+	}
+	if got := m.List(); len(got) != 2 || !got[1].RegisteredAt.Equal(snapshot[2].RegisteredAt) {
+		t.Errorf("expected the kept registration of %v to keep its time, got %+v", frontend, got)
+	}
+
+	if _, err := m.Describe(NewRouteKey(namespace, "unknown")); err == nil {
+		t.Error("expecting an error for unregistered parent, got nil")
+	}
+}
@@ -0,0 +1,49 @@
+package secret
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NewMetadataObjectMonitor returns an ObjectMonitor which watches only the metadata of single objects
+// of the given resource, using the metadata client. Handlers receive *metav1.PartialObjectMetadata,
+// which carries the resourceVersion, labels and annotations, but none of the object's content.
+func NewMetadataObjectMonitor(metadataClient metadata.Interface, gvr schema.GroupVersionResource) ObjectMonitor[*metav1.PartialObjectMetadata] {
+	return newObjectMonitor[*metav1.PartialObjectMetadata](gvr.GroupResource(), createMetadataInformer(metadataClient, gvr))
+}
+
+// NewSecretMetadataMonitor returns an ObjectMonitor for consumers which only need to know that a secret
+// changed, and never read its data. Since the secret data is neither fetched nor cached, it saves memory
+// and keeps the data out of the process. It still needs the permissions to list and watch secrets.
+func NewSecretMetadataMonitor(metadataClient metadata.Interface) ObjectMonitor[*metav1.PartialObjectMetadata] {
+	return NewMetadataObjectMonitor(metadataClient, corev1.SchemeGroupVersion.WithResource("secrets"))
+}
+
+// createMetadataInformer returns a function which creates a metadata-only SharedInformer
+// for monitoring a specific object of the given resource.
+func createMetadataInformer(metadataClient metadata.Interface, gvr schema.GroupVersionResource) func(namespace, name string) cache.SharedInformer {
+	return func(namespace, name string) cache.SharedInformer {
+		fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+		return newObservedInformer(
+			&cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+					options.FieldSelector = fieldSelector
+					return metadataClient.Resource(gvr).Namespace(namespace).List(context.TODO(), options)
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					options.FieldSelector = fieldSelector
+					return metadataClient.Resource(gvr).Namespace(namespace).Watch(context.TODO(), options)
+				},
+			},
+			&metav1.PartialObjectMetadata{},
+		)
+	}
+}
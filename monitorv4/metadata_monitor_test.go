@@ -0,0 +1,102 @@
+package secret
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func fakeSecretMetadata(namespace, name string) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       namespace,
+			ResourceVersion: "1",
+			Labels:          map[string]string{"app": "router"},
+		},
+	}
+}
+
+func TestSecretMetadataMonitor(t *testing.T) {
+	var (
+		namespace  = "ns"
+		secretName = "secret"
+	)
+
+	scenarios := []struct {
+		name         string
+		withSecret   bool
+		expectLabels map[string]string
+		expectErr    bool
+	}{
+		{
+			name:       "secret does not exist in cluster",
+			withSecret: false,
+			expectErr:  true,
+		},
+		{
+			name:         "secret metadata exists in cluster",
+			withSecret:   true,
+			expectLabels: map[string]string{"app": "router"},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if s.withSecret {
+				objects = append(objects, fakeSecretMetadata(namespace, secretName))
+			}
+			scheme := metadatafake.NewTestScheme()
+			scheme.AddKnownTypeWithName(corev1.SchemeGroupVersion.WithKind("Secret"), &metav1.PartialObjectMetadata{})
+			fakeMetadataClient := metadatafake.NewSimpleMetadataClient(scheme, objects...)
+			key := NewObjectKey(namespace, secretName)
+			om := NewSecretMetadataMonitor(fakeMetadataClient).(*objectMonitor[*metav1.PartialObjectMetadata])
+
+			numAdd := 0
+			h, err := om.AddEventHandler(context.TODO(), namespace, secretName, cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+					if _, ok := obj.(*metav1.PartialObjectMetadata); ok {
+						numAdd++
+					}
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, gotErr := om.Get(h)
+			if gotErr != nil && !s.expectErr {
+				t.Errorf("unexpected error %v", gotErr)
+			}
+			if gotErr == nil && s.expectErr {
+				t.Errorf("expecting an error, got nil")
+			}
+			if got != nil && got.Labels["app"] != s.expectLabels["app"] {
+				t.Errorf("expected labels %v got %v", s.expectLabels, got.Labels)
+			}
+			if s.withSecret && numAdd != 1 {
+				t.Errorf("expected 1 add event, got %d", numAdd)
+			}
+			if om.resource != corev1.Resource("secrets") {
+				t.Errorf("unexpected resource %v", om.resource)
+			}
+
+			if err := om.RemoveEventHandler(h); err != nil {
+				t.Error(err)
+			}
+			if _, exist := om.monitors[key]; exist {
+				t.Error("monitor key should be removed from map", key)
+			}
+		})
+	}
+}
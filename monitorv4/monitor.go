@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
@@ -24,6 +25,22 @@ type singleItemMonitor struct {
 	lock     sync.Mutex
 	stopped  bool
 	stopCh   chan struct{}
+	// lastErr is the last list/watch error reported by the informer
+	lastErr error
+	// lastErrTime is when lastErr was reported
+	lastErrTime time.Time
+	// consecutiveFailures is the number of list/watch errors since the last successful list/watch, or event
+	consecutiveFailures int
+	// lastEventTime is when the informer last delivered an event
+	lastEventTime time.Time
+	// newInformer creates a fresh informer on Restart(). Nil if the monitor can not be restarted.
+	newInformer func() cache.SharedInformer
+	// registrations are the handler registrations attached to the informer,
+	// which are re-attached on Restart()
+	registrations map[*objectEventHandlerRegistration]struct{}
+	// ctx is the context the informer was last started with, which the informer
+	// replacing it on Restart() runs with
+	ctx context.Context
 }
 
 // NewObjectKey creates a new ObjectKey for the given namespace and name.
@@ -36,58 +53,214 @@ func NewObjectKey(namespace, name string) ObjectKey {
 
 // newSingleItemMonitor creates a new singleItemMonitor for the given key and informer.
 func newSingleItemMonitor(key ObjectKey, informer cache.SharedInformer) *singleItemMonitor {
-	return &singleItemMonitor{
-		key:      key,
-		informer: informer,
-		stopped:  true,
-		stopCh:   make(chan struct{}),
+	i := &singleItemMonitor{
+		key:           key,
+		informer:      informer,
+		stopped:       true,
+		stopCh:        make(chan struct{}),
+		registrations: map[*objectEventHandlerRegistration]struct{}{},
 	}
+	i.observe(informer)
+	return i
+}
+
+// newRestartableSingleItemMonitor creates a new singleItemMonitor for the given key,
+// which uses newInformer to create its informer, and to recreate it on Restart().
+func newRestartableSingleItemMonitor(key ObjectKey, newInformer func() cache.SharedInformer) *singleItemMonitor {
+	i := newSingleItemMonitor(key, newInformer())
+	i.newInformer = newInformer
+	return i
+}
+
+// observe sets up the informer to report its list/watch errors and events to the monitor,
+// and its successful lists and watches if created by newObservedInformer.
+// Must be called before the informer is run.
+func (i *singleItemMonitor) observe(informer cache.SharedInformer) {
+	if err := informer.SetWatchErrorHandler(i.watchErrorHandler); err != nil {
+		klog.Error(err)
+	}
+	if observed, ok := informer.(*observedInformer); ok {
+		observed.listWatch.reportTo(i.recordListWatch)
+	}
+	if _, err := informer.AddEventHandler(statusHandler{monitor: i}); err != nil {
+		klog.Error(err)
+	}
+}
+
+// watchErrorHandler records the list/watch error and passes it on to the default handler.
+func (i *singleItemMonitor) watchErrorHandler(r *cache.Reflector, err error) {
+	i.lock.Lock()
+	i.lastErr = err
+	i.lastErrTime = time.Now()
+	i.consecutiveFailures++
+	i.lock.Unlock()
+
+	cache.DefaultWatchErrorHandler(r, err)
+}
+
+// recordEvent records the time of an event delivered by the informer,
+// which shows that listing/watching works again.
+func (i *singleItemMonitor) recordEvent() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.lastEventTime = time.Now()
+	i.consecutiveFailures = 0
+}
+
+// recordListWatch records a successful list or watch of the informer,
+// which shows that listing/watching works again.
+func (i *singleItemMonitor) recordListWatch() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.consecutiveFailures = 0
+}
+
+// Status returns the health of the informer.
+func (i *singleItemMonitor) Status() MonitorStatus {
+	informer, _ := i.current()
+	synced := informer.HasSynced()
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return MonitorStatus{
+		Key:                 i.key,
+		Synced:              synced,
+		LastError:           i.lastErr,
+		LastErrorTime:       i.lastErrTime,
+		ConsecutiveFailures: i.consecutiveFailures,
+		LastEventTime:       i.lastEventTime,
+	}
+}
+
+// LastError returns the last list/watch error reported by the informer.
+func (i *singleItemMonitor) LastError() error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.lastErr
+}
+
+// current returns the current informer and its stop channel, which are replaced on Restart().
+func (i *singleItemMonitor) current() (cache.SharedInformer, chan struct{}) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.informer, i.stopCh
+}
+
+// WaitForSync waits until the informer's cache has synced, ctx is done, timeout passes
+// or StopInformer() is called. An informer replaced by Restart() meanwhile is waited for in turn.
+func (i *singleItemMonitor) WaitForSync(ctx context.Context, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		_, stopCh := i.current()
+		err := i.waitForInformer(ctx, stopCh, time.Until(deadline))
+		if err == nil || !i.restarted(stopCh) {
+			return err
+		}
+	}
+}
+
+// restarted returns true if the informer of stopCh was replaced by Restart(), which runs in its place.
+func (i *singleItemMonitor) restarted(stopCh chan struct{}) bool {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.stopCh != stopCh && !i.stopped
+}
+
+// waitForInformer waits until the informer of stopCh has synced, ctx is done, timeout passes or it is stopped.
+func (i *singleItemMonitor) waitForInformer(ctx context.Context, stopCh chan struct{}, timeout time.Duration) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := waitForSync(ctx, i.key, timeout, i.HasSynced, i.LastError); err != nil {
+		select {
+		case <-stopCh:
+			return fmt.Errorf("informer for %v stopped before cache sync", i.key)
+		default:
+		}
+		return err
+	}
+	return nil
 }
 
 // HasSynced returns true if the informer's cache has been successfully synced.
 func (i *singleItemMonitor) HasSynced() bool {
-	return i.informer.HasSynced()
+	informer, _ := i.current()
+	return informer.HasSynced()
 }
 
 // StartInformer starts and runs the informer util the provided context is canceled,
 // or StopInformer() is called. It will block, so call via goroutine.
 func (i *singleItemMonitor) StartInformer(ctx context.Context) {
+	if informer, stopCh, ok := i.markStarted(ctx); ok {
+		informer.Run(stopCh)
+	}
+}
+
+// start runs the informer in the background like StartInformer, but returns only
+// after the monitor is marked as running, so that a following StopInformer()
+// always stops the informer.
+func (i *singleItemMonitor) start(ctx context.Context) {
+	if informer, stopCh, ok := i.markStarted(ctx); ok {
+		go informer.Run(stopCh)
+	}
+}
+
+// markStarted marks the monitor as running and stops it once ctx is canceled.
+// Returns the informer and stop channel to run it with, and
+// false if the monitor is already running.
+func (i *singleItemMonitor) markStarted(ctx context.Context) (cache.SharedInformer, chan struct{}, bool) {
 	i.lock.Lock()
+	defer i.lock.Unlock()
 
 	if !i.stopped {
 		klog.Warning("informer is already running")
-		i.lock.Unlock()
-		return
+		return nil, nil, false
 	}
 
-	go func() {
-		select {
-		case <-ctx.Done():
-			klog.Info("stopping informer due to context cancellation")
-			if !i.StopInformer() {
-				klog.Error("failed to stop informer")
-			}
-		// this case is required to exit from the goroutine
-		// after normal StopInformer() call
-		case <-i.stopCh:
-			klog.Info("successfully stopped")
-		}
-	}()
+	i.ctx = ctx
+	i.stopped = false
+	go i.stopOnDone(ctx, i.stopCh)
 
 	klog.Info("starting informer")
-	i.stopped = false
-	i.lock.Unlock()
+	return i.informer, i.stopCh, true
+}
 
-	i.informer.Run(i.stopCh)
+// stopOnDone stops the informer of stopCh once ctx is canceled.
+func (i *singleItemMonitor) stopOnDone(ctx context.Context, stopCh chan struct{}) {
+	select {
+	case <-ctx.Done():
+		klog.Info("stopping informer due to context cancellation")
+		if !i.stop(stopCh) {
+			klog.Error("failed to stop informer")
+		}
+	// this case is required to exit from the goroutine
+	// after normal StopInformer() call
+	case <-stopCh:
+		klog.Info("successfully stopped")
+	}
 }
 
 // StopInformer stops the informer.
 // Retuns false if called twice, or before StartInformer(); true otherwise.
 func (i *singleItemMonitor) StopInformer() bool {
+	_, stopCh := i.current()
+	return i.stop(stopCh)
+}
+
+// stop stops the informer, unless it was already stopped,
+// or has been replaced by Restart() since stopCh was taken.
+func (i *singleItemMonitor) stop(stopCh chan struct{}) bool {
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
-	if i.stopped {
+	if i.stopped || i.stopCh != stopCh {
 		return false
 	}
 	i.stopped = true
@@ -96,29 +269,130 @@ func (i *singleItemMonitor) StopInformer() bool {
 	return true
 }
 
+// Restart replaces the informer with a fresh informer and stop channel, and stops the old one if it
+// is running. The handlers which are still registered are re-attached to the new informer, so their
+// registrations stay valid. The new informer is set up before the old one is stopped, which is kept
+// if that fails. It runs in the background with the context the monitor was last started with,
+// until it is canceled or StopInformer() is called.
+func (i *singleItemMonitor) Restart() error {
+	if i.newInformer == nil {
+		return fmt.Errorf("informer for %v can not be restarted", i.key)
+	}
+
+	informer := i.newInformer()
+	i.observe(informer)
+	handles, err := i.addHandlers(informer, i.currentRegistrations())
+	if err != nil {
+		return err
+	}
+
+	i.lock.Lock()
+	// reconcile the handlers attached or removed meanwhile
+	added := map[*objectEventHandlerRegistration]struct{}{}
+	for r := range i.registrations {
+		if _, exists := handles[r]; !exists {
+			added[r] = struct{}{}
+		}
+	}
+	for r, handle := range handles {
+		if _, exists := i.registrations[r]; !exists {
+			if err := informer.RemoveEventHandler(handle); err != nil {
+				klog.Error(err)
+			}
+			delete(handles, r)
+		}
+	}
+	addedHandles, err := i.addHandlers(informer, added)
+	if err != nil {
+		i.lock.Unlock()
+		return err
+	}
+	for r, handle := range addedHandles {
+		handles[r] = handle
+	}
+
+	if !i.stopped {
+		close(i.stopCh)
+	}
+	ctx := i.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	i.informer = informer
+	i.stopCh = make(chan struct{})
+	i.lastErr = nil
+	i.consecutiveFailures = 0
+	for r, handle := range handles {
+		r.setHandler(handle)
+	}
+	i.stopped = false
+	stopCh := i.stopCh
+	go i.stopOnDone(ctx, stopCh)
+	i.lock.Unlock()
+
+	klog.Info("restarting informer", " key ", i.key)
+	go informer.Run(stopCh)
+	return nil
+}
+
+// currentRegistrations returns a copy of the registrations attached to the informer.
+func (i *singleItemMonitor) currentRegistrations() map[*objectEventHandlerRegistration]struct{} {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	registrations := make(map[*objectEventHandlerRegistration]struct{}, len(i.registrations))
+	for r := range i.registrations {
+		registrations[r] = struct{}{}
+	}
+	return registrations
+}
+
+// addHandlers adds the handlers of the registrations to informer, which is not running yet,
+// and returns their handles.
+func (i *singleItemMonitor) addHandlers(informer cache.SharedInformer, registrations map[*objectEventHandlerRegistration]struct{}) (map[*objectEventHandlerRegistration]cache.ResourceEventHandlerRegistration, error) {
+	handles := make(map[*objectEventHandlerRegistration]cache.ResourceEventHandlerRegistration, len(registrations))
+	for r := range registrations {
+		handle, err := informer.AddEventHandler(r.informerHandler())
+		if err != nil {
+			return nil, err
+		}
+		handles[r] = handle
+	}
+	return handles, nil
+}
+
 // AddEventHandler adds an event handler to the informer and returns
-// secretEventHandlerRegistration after populating objectKey and registration.
-func (i *singleItemMonitor) AddEventHandler(handler cache.ResourceEventHandler) (SecretEventHandlerRegistration, error) {
+// objectEventHandlerRegistration after populating objectKey and registration.
+func (i *singleItemMonitor) AddEventHandler(handler cache.ResourceEventHandler) (ObjectEventHandlerRegistration, error) {
+	r := newObjectEventHandlerRegistration(i.key, handler)
+	if err := i.attach(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// attach adds the handler of an existing registration to the informer
+// and points the registration to the informer's handle.
+func (i *singleItemMonitor) attach(r *objectEventHandlerRegistration) error {
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
 	if i.stopped {
-		return nil, fmt.Errorf("can not add handler %v to already stopped informer", handler)
+		return fmt.Errorf("can not add handler %v to already stopped informer", r.handler)
 	}
 
-	registration, err := i.informer.AddEventHandler(handler)
+	registration, err := i.informer.AddEventHandler(r.informerHandler())
 	if err != nil {
-		return nil, err
+		return err
 	}
+	r.setHandler(registration)
+	i.registrations[r] = struct{}{}
 
-	return &secretEventHandlerRegistration{
-		ResourceEventHandlerRegistration: registration,
-		objectKey:                        i.key,
-	}, nil
+	return nil
 }
 
 // RemoveEventHandler removes an event handler from the informer.
-func (i *singleItemMonitor) RemoveEventHandler(handle SecretEventHandlerRegistration) error {
+func (i *singleItemMonitor) RemoveEventHandler(handle ObjectEventHandlerRegistration) error {
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
@@ -126,12 +400,24 @@ func (i *singleItemMonitor) RemoveEventHandler(handle SecretEventHandlerRegistra
 		return fmt.Errorf("can not remove handler %v from stopped informer", handle.GetHandler())
 	}
 
-	return i.informer.RemoveEventHandler(handle.GetHandler())
+	if err := i.informer.RemoveEventHandler(handle.GetHandler()); err != nil {
+		return err
+	}
+	if r, ok := handle.(*objectEventHandlerRegistration); ok {
+		delete(i.registrations, r)
+	}
+	return nil
 }
 
 // GetItem returns the accumulator being monitored
 // by informer, using keyFunc (namespace/name).
 func (i *singleItemMonitor) GetItem() (item interface{}, exists bool, err error) {
 	keyFunc := i.key.Namespace + "/" + i.key.Name
-	return i.informer.GetStore().GetByKey(keyFunc)
+	return i.GetStore().GetByKey(keyFunc)
+}
+
+// GetStore returns the store of the current informer.
+func (i *singleItemMonitor) GetStore() cache.Store {
+	informer, _ := i.current()
+	return informer.GetStore()
 }
@@ -3,17 +3,14 @@ package secret
 import (
 	"context"
 	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/klog/v2"
 )
 
 func fakeMonitor(ctx context.Context, fakeKubeClient *fake.Clientset, key ObjectKey) *singleItemMonitor {
@@ -21,42 +18,6 @@ func fakeMonitor(ctx context.Context, fakeKubeClient *fake.Clientset, key Object
 	return newSingleItemMonitor(key, sharedInformer)
 }
 
-// fakeSecretInformer will list/watch only one secret inside a namespace
-func fakeSecretInformer(ctx context.Context, fakeKubeClient *fake.Clientset, namespace, name string) cache.SharedInformer {
-	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
-	klog.Info(fieldSelector)
-	return cache.NewSharedInformer(&cache.ListWatch{
-		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-			options.FieldSelector = fieldSelector
-			return fakeKubeClient.CoreV1().Secrets(namespace).List(ctx, options)
-		},
-		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-			options.FieldSelector = fieldSelector
-			return fakeKubeClient.CoreV1().Secrets(namespace).Watch(ctx, options)
-		},
-	},
-		&corev1.Secret{},
-		0,
-	)
-}
-
-func fakeSecret(namespace, name string) *corev1.Secret {
-	return &corev1.Secret{
-		Type: corev1.SecretTypeOpaque,
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "Secret",
-			APIVersion: "v1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
-			Namespace: namespace,
-		},
-		Data: map[string][]byte{
-			"test": {1, 2, 3, 4},
-		},
-	}
-}
-
 func TestStartInformer(t *testing.T) {
 	scenarios := []struct {
 		name      string
@@ -352,3 +313,173 @@ func TestGetItem(t *testing.T) {
 		})
 	}
 }
+
+func TestRestartInformer(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		name      = "secretName"
+		secret    = fakeSecret(namespace, name)
+	)
+	scenarios := []struct {
+		name         string
+		restartable  bool
+		withStart    bool
+		expectErr    bool
+		expectNumAdd int32
+	}{
+		{
+			name:      "restart without informer factory",
+			withStart: true,
+			expectErr: true,
+		},
+		{
+			name:         "restart running informer",
+			restartable:  true,
+			withStart:    true,
+			expectNumAdd: 2,
+		},
+		{
+			name:         "restart stopped informer",
+			restartable:  true,
+			withStart:    false,
+			expectNumAdd: 2,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			fakeKubeClient := fake.NewSimpleClientset(secret)
+			key := NewObjectKey(namespace, name)
+			var monitor *singleItemMonitor
+			if s.restartable {
+				monitor = newRestartableSingleItemMonitor(key, func() cache.SharedInformer {
+					return fakeSecretInformer(context.TODO(), fakeKubeClient, namespace, name)
+				})
+			} else {
+				monitor = fakeMonitor(context.TODO(), fakeKubeClient, key)
+			}
+
+			var numAdd atomic.Int32
+			monitor.start(context.TODO())
+			handle, err := monitor.AddEventHandler(cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) { numAdd.Add(1) },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := monitor.WaitForSync(context.TODO(), time.Second); err != nil {
+				t.Fatal(err)
+			}
+			if !s.withStart {
+				monitor.StopInformer()
+			}
+
+			err = monitor.Restart()
+			if (err != nil) != s.expectErr {
+				t.Fatalf("expected error %t, got %v", s.expectErr, err)
+			}
+			if s.expectErr {
+				return
+			}
+			defer monitor.StopInformer()
+
+			if err := monitor.WaitForSync(context.TODO(), time.Second); err != nil {
+				t.Fatal(err)
+			}
+			if !handle.HasSynced() {
+				t.Error("re-attached handler is not synced")
+			}
+			if err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, time.Second, true, func(context.Context) (bool, error) {
+				return numAdd.Load() == s.expectNumAdd, nil
+			}); err != nil {
+				t.Errorf("expected %d add events, got %d", s.expectNumAdd, numAdd.Load())
+			}
+			if _, exists, _ := monitor.GetItem(); !exists {
+				t.Error("item does not exist after restart")
+			}
+			if err := monitor.RemoveEventHandler(handle); err != nil {
+				t.Errorf("failed to remove re-attached handler: %v", err)
+			}
+		})
+	}
+}
+
+func TestRestartInformerContext(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		name      = "secretName"
+		key       = NewObjectKey(namespace, name)
+	)
+	fakeKubeClient := fake.NewSimpleClientset(fakeSecret(namespace, name))
+	monitor := newRestartableSingleItemMonitor(key, func() cache.SharedInformer {
+		return fakeSecretInformer(context.TODO(), fakeKubeClient, namespace, name)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	monitor.start(ctx)
+	if err := monitor.WaitForSync(context.TODO(), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor.WaitForSync(context.TODO(), time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// the fresh informer stops with the context the monitor was started with
+	cancel()
+	if err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, time.Second, true, func(context.Context) (bool, error) {
+		monitor.lock.Lock()
+		defer monitor.lock.Unlock()
+		return monitor.stopped, nil
+	}); err != nil {
+		t.Error("expected the restarted informer to stop once the context is canceled")
+	}
+}
+
+func TestRestartInformerFailure(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		name      = "secretName"
+		key       = NewObjectKey(namespace, name)
+		created   atomic.Int32
+	)
+	fakeKubeClient := fake.NewSimpleClientset(fakeSecret(namespace, name))
+	monitor := newRestartableSingleItemMonitor(key, func() cache.SharedInformer {
+		informer := fakeSecretInformer(context.TODO(), fakeKubeClient, namespace, name)
+		// the informers replacing the first one reject handlers
+		if created.Add(1) > 1 {
+			stopCh := make(chan struct{})
+			close(stopCh)
+			informer.Run(stopCh)
+		}
+		return informer
+	})
+
+	monitor.start(context.TODO())
+	defer monitor.StopInformer()
+	handle, err := monitor.AddEventHandler(cache.ResourceEventHandlerFuncs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor.WaitForSync(context.TODO(), time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := monitor.Restart(); err == nil {
+		t.Fatal("expected restart to fail")
+	}
+
+	// the old informer keeps serving the handler
+	if !handle.HasSynced() {
+		t.Error("handler is not synced after failed restart")
+	}
+	if _, exists, _ := monitor.GetItem(); !exists {
+		t.Error("item does not exist after failed restart")
+	}
+	if err := monitor.RemoveEventHandler(handle); err != nil {
+		t.Errorf("failed to remove handler after failed restart: %v", err)
+	}
+}
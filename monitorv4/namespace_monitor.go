@@ -0,0 +1,543 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// namespaceMonitor monitors all objects of a namespace using a single namespace-wide informer.
+// The informer has a single handler, the dispatcher, which notifies the handlers registered for
+// the name of each object, one at a time. A handler added to a running informer is first notified
+// about its object if it is cached, and then about the following events.
+type namespaceMonitor struct {
+	itemMonitor *singleItemMonitor
+	// ctx is owned by the monitor, so that the namespace-wide informer runs regardless of the caller
+	// which started it, until it is canceled by stop()
+	ctx    context.Context
+	cancel context.CancelFunc
+	// dispatcher is the registration of the handler of the namespace-wide informer
+	dispatcher *objectEventHandlerRegistration
+
+	// dispatchLock serializes the notifications of the dispatcher with the first notification
+	// of the handlers being added, and with the refreshes of objects
+	dispatchLock sync.Mutex
+	// objectsLock guards objects, which are only written with dispatchLock held as well
+	objectsLock sync.RWMutex
+	// objects are the objects last dispatched or refreshed, keyed by name
+	objects map[string]interface{}
+
+	lock sync.RWMutex
+	// handlers are the registered handlers, keyed by object name
+	handlers map[string]map[*objectEventHandlerRegistration]struct{}
+	// joining are the registered handlers which have not been notified about their object yet,
+	// which the dispatcher skips
+	joining map[*objectEventHandlerRegistration]struct{}
+}
+
+// newNamespaceMonitor creates a new namespaceMonitor for the given namespace,
+// using newInformer to create the namespace-wide informer.
+func newNamespaceMonitor(namespace string, newInformer func() cache.SharedInformer) *namespaceMonitor {
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &namespaceMonitor{
+		itemMonitor: newRestartableSingleItemMonitor(NewObjectKey(namespace, ""), newInformer),
+		ctx:         ctx,
+		cancel:      cancel,
+		objects:     map[string]interface{}{},
+		handlers:    map[string]map[*objectEventHandlerRegistration]struct{}{},
+		joining:     map[*objectEventHandlerRegistration]struct{}{},
+	}
+	n.dispatcher = newObjectEventHandlerRegistration(n.itemMonitor.key, namespaceDispatcher{n})
+	return n
+}
+
+// start starts the namespace-wide informer and waits, bounded by syncTimeout, until the
+// dispatcher has been notified about the cached objects. On failure the informer is stopped again.
+func (n *namespaceMonitor) start(syncTimeout time.Duration) error {
+	n.itemMonitor.start(n.ctx)
+	if err := n.itemMonitor.attach(n.dispatcher); err != nil {
+		n.stop()
+		return err
+	}
+
+	// wait for first sync
+	if err := waitForSync(n.ctx, n.itemMonitor.key, syncTimeout, n.dispatcher.HasSynced, n.itemMonitor.LastError); err != nil {
+		n.stop()
+		return err
+	}
+	return nil
+}
+
+// stop stops the namespace-wide informer.
+func (n *namespaceMonitor) stop() {
+	if !n.itemMonitor.StopInformer() {
+		klog.Error("namespace informer already stopped", " namespace ", n.itemMonitor.key.Namespace)
+	}
+	n.cancel()
+}
+
+// addHandler registers r for the name of its object. The dispatcher skips r until join is called.
+func (n *namespaceMonitor) addHandler(r *objectEventHandlerRegistration) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	name := r.GetKey().Name
+	if _, exists := n.handlers[name]; !exists {
+		n.handlers[name] = map[*objectEventHandlerRegistration]struct{}{}
+	}
+	n.handlers[name][r] = struct{}{}
+	n.joining[r] = struct{}{}
+}
+
+// join notifies r about its object if it is cached, as the informer does for a handler added to it,
+// and lets the dispatcher notify r about the following events. r is marked as ready after,
+// unless it was removed meanwhile.
+func (n *namespaceMonitor) join(r *objectEventHandlerRegistration) {
+	n.dispatchLock.Lock()
+	defer n.dispatchLock.Unlock()
+
+	n.lock.Lock()
+	_, joining := n.joining[r]
+	delete(n.joining, r)
+	n.lock.Unlock()
+	if !joining {
+		return
+	}
+
+	if obj, exists := n.objects[r.GetKey().Name]; exists {
+		registrationHandler{r}.OnAdd(obj, true)
+	}
+	r.setHandler(n.dispatcher)
+	r.markReady(nil)
+}
+
+// removeHandler unregisters r. Returns false if r was not registered.
+func (n *namespaceMonitor) removeHandler(r *objectEventHandlerRegistration) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	name := r.GetKey().Name
+	if _, exists := n.handlers[name][r]; !exists {
+		return false
+	}
+	delete(n.handlers[name], r)
+	if len(n.handlers[name]) == 0 {
+		delete(n.handlers, name)
+	}
+	delete(n.joining, r)
+	return true
+}
+
+// handlersFor returns the handlers registered for the object name, which have joined.
+func (n *namespaceMonitor) handlersFor(name string) []*objectEventHandlerRegistration {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	ret := make([]*objectEventHandlerRegistration, 0, len(n.handlers[name]))
+	for r := range n.handlers[name] {
+		if _, joining := n.joining[r]; !joining {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
+// setObject records obj as the object with the given name, nil if deleted.
+// Must be called with dispatchLock held.
+func (n *namespaceMonitor) setObject(name string, obj interface{}) {
+	n.objectsLock.Lock()
+	defer n.objectsLock.Unlock()
+	if obj == nil {
+		delete(n.objects, name)
+		return
+	}
+	n.objects[name] = obj
+}
+
+// refresh replaces the object of r with obj, fetched from the API server after the data stripped
+// from the cached object became needed, and notifies r about the change. The object is only replaced
+// if it is of the same version as the cached one; false is returned if the dispatcher has yet to
+// be notified about the version of obj. Objects without resourceVersion, e.g. of fake clientsets, are
+// taken as the same version. Nil obj means the object does not exist. r is skipped if it was removed.
+func (n *namespaceMonitor) refresh(r *objectEventHandlerRegistration, obj interface{}) bool {
+	n.dispatchLock.Lock()
+	defer n.dispatchLock.Unlock()
+
+	name := r.GetKey().Name
+	n.lock.RLock()
+	_, registered := n.handlers[name][r]
+	n.lock.RUnlock()
+	if !registered {
+		return true
+	}
+
+	cached, exists := n.objects[name]
+	if obj == nil || !exists {
+		return obj == nil && !exists
+	}
+	_, cachedVersion, _ := objectVersion(cached)
+	_, version, _ := objectVersion(obj)
+	if cachedVersion != version {
+		return false
+	}
+	// refreshed already for another registration
+	if reflect.DeepEqual(cached, obj) {
+		return true
+	}
+	n.setObject(name, obj)
+	registrationHandler{r}.OnUpdate(cached, obj)
+	return true
+}
+
+// namespaceDispatcher is the handler of a namespace-wide informer, which notifies
+// the handlers registered for the name of each object.
+type namespaceDispatcher struct {
+	n *namespaceMonitor
+}
+
+// objectName returns the name of obj, which may be a tombstone.
+func objectName(obj interface{}) (string, bool) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		klog.Error(err)
+		return "", false
+	}
+	_, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		klog.Error(err)
+		return "", false
+	}
+	return name, true
+}
+
+func (d namespaceDispatcher) OnAdd(obj interface{}, isInInitialList bool) {
+	name, ok := objectName(obj)
+	if !ok {
+		return
+	}
+	d.n.dispatchLock.Lock()
+	defer d.n.dispatchLock.Unlock()
+
+	last, exists := d.n.objects[name]
+	d.n.setObject(name, obj)
+	for _, r := range d.n.handlersFor(name) {
+		// a restarted informer replays its cache, of which the objects already delivered are dropped
+		if exists && isInInitialList {
+			if !sameObject(last, obj) {
+				registrationHandler{r}.OnUpdate(last, obj)
+			}
+			continue
+		}
+		registrationHandler{r}.OnAdd(obj, isInInitialList)
+	}
+}
+
+func (d namespaceDispatcher) OnUpdate(oldObj, newObj interface{}) {
+	name, ok := objectName(newObj)
+	if !ok {
+		return
+	}
+	d.n.dispatchLock.Lock()
+	defer d.n.dispatchLock.Unlock()
+
+	d.n.setObject(name, newObj)
+	for _, r := range d.n.handlersFor(name) {
+		registrationHandler{r}.OnUpdate(oldObj, newObj)
+	}
+}
+
+func (d namespaceDispatcher) OnDelete(obj interface{}) {
+	name, ok := objectName(obj)
+	if !ok {
+		return
+	}
+	d.n.dispatchLock.Lock()
+	defer d.n.dispatchLock.Unlock()
+
+	if _, exists := d.n.objects[name]; !exists {
+		return
+	}
+	d.n.setObject(name, nil)
+	for _, r := range d.n.handlersFor(name) {
+		registrationHandler{r}.OnDelete(obj)
+	}
+}
+
+// hasName returns true if any handler is registered for the object name.
+func (n *namespaceMonitor) hasName(name string) bool {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	_, exists := n.handlers[name]
+	return exists
+}
+
+// numNames returns the number of object names with registered handlers.
+func (n *namespaceMonitor) numNames() int {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	return len(n.handlers)
+}
+
+// numHandlers returns the number of registered handlers, across all object names.
+func (n *namespaceMonitor) numHandlers() int {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	count := 0
+	for _, registrations := range n.handlers {
+		count += len(registrations)
+	}
+	return count
+}
+
+// registrations returns a copy of the registered handlers, keyed by object name.
+func (n *namespaceMonitor) registrations() map[string][]*objectEventHandlerRegistration {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	ret := make(map[string][]*objectEventHandlerRegistration, len(n.handlers))
+	for name, registrations := range n.handlers {
+		for r := range registrations {
+			ret[name] = append(ret[name], r)
+		}
+	}
+	return ret
+}
+
+// getItem returns the object with the given name last dispatched by the namespace-wide informer,
+// or refreshed since.
+func (n *namespaceMonitor) getItem(name string) (item interface{}, exists bool, err error) {
+	n.objectsLock.RLock()
+	defer n.objectsLock.RUnlock()
+	item, exists = n.objects[name]
+	return item, exists, nil
+}
+
+// numMonitoredNames returns the number of object names monitored in the namespace.
+func (o *objectMonitor[T]) numMonitoredNames(namespace string) int {
+	if n, exists := o.namespaces[namespace]; exists {
+		return n.numNames()
+	}
+	count := 0
+	for key := range o.monitors {
+		if key.Namespace == namespace {
+			count++
+		}
+	}
+	return count
+}
+
+// namespaceSwitch is a switch of a namespace between per-object informers and a namespace-wide informer
+// in progress.
+type namespaceSwitch struct {
+	// pending are the registrations of objects without a per-object informer, added while switching to
+	// a namespace-wide informer. They are marked ready once it is swapped in, or failed if it does not sync.
+	pending map[*objectEventHandlerRegistration]struct{}
+}
+
+func newNamespaceSwitch() *namespaceSwitch {
+	return &namespaceSwitch{
+		pending: map[*objectEventHandlerRegistration]struct{}{},
+	}
+}
+
+// removePendingHandler removes r if it waits for a namespace-wide informer. Returns false if it does not.
+func (o *objectMonitor[T]) removePendingHandler(r *objectEventHandlerRegistration) bool {
+	sw, exists := o.switching[r.GetKey().Namespace]
+	if !exists {
+		return false
+	}
+	if _, exists := sw.pending[r]; !exists {
+		return false
+	}
+	delete(sw.pending, r)
+	r.markReady(fmt.Errorf("%s handler removed before cache sync", o.resource.String()))
+	klog.Info(o.resource.String(), " handler removed", " item key", r.GetKey())
+	return true
+}
+
+// switchToNamespaceMonitor replaces the per-object informers of the namespace with a single namespace-wide
+// informer, moving their handlers and the pending registrations of sw over to it. The namespace-wide informer
+// is started and synced without holding the lock, which is only taken to swap it in; per-object informers
+// started meanwhile are moved as well. The handlers are marked ready once the namespace-wide informer notified
+// them about their objects, and the pending registrations are failed with the sync error if it does not sync.
+// The namespace must have been marked as switching with sw by the caller.
+func (o *objectMonitor[T]) switchToNamespaceMonitor(namespace string, sw *namespaceSwitch) {
+	n := newNamespaceMonitor(namespace, o.transformed(func() cache.SharedInformer {
+		return o.createNamespaceInformer(namespace)
+	}))
+	err := n.start(o.syncTimeout)
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	delete(o.switching, namespace)
+	if err != nil {
+		klog.Error(o.resource.String(), " namespace informer failed to sync", " namespace ", namespace, " err ", err)
+		for r := range sw.pending {
+			r.markReady(err)
+		}
+		return
+	}
+
+	// move the handlers from the per-object informers and stop them. The namespace-wide informer
+	// notifies each handler about its cached object, which is dropped if it was already delivered.
+	added := []*objectEventHandlerRegistration{}
+	for key, m := range o.monitors {
+		if key.Namespace != namespace {
+			continue
+		}
+		for r := range m.registrations {
+			if err := m.itemMonitor.RemoveEventHandler(r); err != nil {
+				klog.Error(err)
+			}
+			r.move()
+			n.addHandler(r)
+			added = append(added, r)
+		}
+		if !m.itemMonitor.StopInformer() {
+			klog.Error(o.resource.String(), " informer already stopped", " item key", key)
+		}
+		delete(o.monitors, key)
+	}
+	for r := range sw.pending {
+		n.addHandler(r)
+		added = append(added, r)
+	}
+	// the handlers were all removed meanwhile
+	if n.numNames() == 0 {
+		n.stop()
+		return
+	}
+	o.namespaces[namespace] = n
+	klog.Info(o.resource.String(), " switched to namespace informer", " namespace ", namespace)
+
+	// the handlers are marked ready once the namespace-wide informer is swapped in
+	for _, r := range added {
+		go n.join(r)
+	}
+}
+
+// switchToItemMonitors replaces the namespace-wide informer n of the namespace with per-object informers,
+// moving its handlers over to them. The per-object informers are started and synced concurrently without
+// holding the lock, which is only taken to swap them in. The namespace-wide informer is kept if any of them
+// fails to start, or if handlers for other names were added to it meanwhile.
+// The namespace must have been marked as switching by the caller.
+func (o *objectMonitor[T]) switchToItemMonitors(namespace string, n *namespaceMonitor) error {
+	names := n.registrations()
+	monitors := make(map[ObjectKey]*monitoredItem, len(names))
+	errs := make(chan error, len(names))
+	var wg sync.WaitGroup
+	var monitorsLock sync.Mutex
+	for name := range names {
+		key := NewObjectKey(namespace, name)
+		name := name
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// the per-object informers outlive the namespace-wide informer, which is stopped once they are swapped in
+			m, err := startMonitoredItem(context.Background(), key, o.transformed(func() cache.SharedInformer {
+				return o.createInformer(namespace, name)
+			}), o.syncTimeout)
+			if err != nil {
+				errs <- err
+				return
+			}
+			monitorsLock.Lock()
+			monitors[key] = m
+			monitorsLock.Unlock()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	delete(o.switching, namespace)
+	stopAll := func() {
+		for _, m := range monitors {
+			m.itemMonitor.StopInformer()
+		}
+	}
+	if err := <-errs; err != nil {
+		stopAll()
+		return err
+	}
+	// the namespace-wide informer was stopped meanwhile, since its last handler was removed
+	if o.namespaces[namespace] != n {
+		stopAll()
+		return nil
+	}
+	registrations := n.registrations()
+	for name := range registrations {
+		if _, exists := monitors[NewObjectKey(namespace, name)]; !exists {
+			stopAll()
+			return fmt.Errorf("%s handler added for %s/%s while switching to per-object informers", o.resource.String(), namespace, name)
+		}
+	}
+
+	for key, m := range monitors {
+		for _, r := range registrations[key.Name] {
+			if !n.removeHandler(r) {
+				continue
+			}
+			r.move()
+			if err := m.itemMonitor.attach(r); err != nil {
+				klog.Error(err)
+				continue
+			}
+			m.registrations[r] = struct{}{}
+			m.numHandlers.Add(1)
+			r.markReady(nil)
+		}
+		// the handlers of the name were removed meanwhile
+		if m.numHandlers.Load() == 0 {
+			m.itemMonitor.StopInformer()
+			continue
+		}
+		o.monitors[key] = m
+	}
+	n.stop()
+	delete(o.namespaces, namespace)
+	klog.Info(o.resource.String(), " switched to per-object informers", " namespace ", namespace)
+
+	return nil
+}
+
+// removeNamespaceHandler removes a handler from the namespace-wide informer. The informer is
+// stopped if no handlers are left, and replaced with per-object informers in the background once
+// the number of monitored names drops to half of the threshold, so that a namespace does not keep
+// switching back and forth around the threshold.
+func (o *objectMonitor[T]) removeNamespaceHandler(handlerRegistration ObjectEventHandlerRegistration) error {
+	key := handlerRegistration.GetKey()
+
+	n, exists := o.namespaces[key.Namespace]
+	r, ok := handlerRegistration.(*objectEventHandlerRegistration)
+	if !exists || !ok || !n.removeHandler(r) {
+		return fmt.Errorf("%s monitor already removed for item key %v", o.resource.String(), key)
+	}
+	r.markReady(fmt.Errorf("%s handler removed before cache sync", o.resource.String()))
+	klog.Info(o.resource.String(), " handler removed from namespace informer", " item key", key)
+
+	_, switching := o.switching[key.Namespace]
+	switch numNames := n.numNames(); {
+	case numNames == 0:
+		n.stop()
+		delete(o.namespaces, key.Namespace)
+		klog.Info(o.resource.String(), " namespace informer stopped", " namespace ", key.Namespace)
+	case numNames <= o.namespaceThreshold/2 && !switching:
+		o.switching[key.Namespace] = newNamespaceSwitch()
+		go func() {
+			if err := o.switchToItemMonitors(key.Namespace, n); err != nil {
+				klog.Error(err)
+			}
+		}()
+	}
+
+	return nil
+}
@@ -0,0 +1,673 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// ObjectEventHandlerRegistration is for registering and unregistering event handlers for object monitoring.
+type ObjectEventHandlerRegistration interface {
+	cache.ResourceEventHandlerRegistration
+
+	GetKey() ObjectKey
+	GetHandler() cache.ResourceEventHandlerRegistration
+}
+
+// AsyncObjectEventHandlerRegistration is an ObjectEventHandlerRegistration
+// whose informer may still be syncing when it is returned.
+type AsyncObjectEventHandlerRegistration interface {
+	ObjectEventHandlerRegistration
+
+	// Ready returns a channel which is closed once the informer has synced, or failed to sync.
+	Ready() <-chan struct{}
+	// Err returns the error which occurred while syncing the informer, if any.
+	// It must only be called after the Ready channel is closed.
+	Err() error
+}
+
+// ObjectMonitor helps in monitoring and handling a specific namespaced object of type T using singleItemMonitor.
+type ObjectMonitor[T runtime.Object] interface {
+	// AddEventHandler adds an event handler to the monitor for a specific object in the given namespace.
+	// The handler will be notified of events related to the "specified" object only.
+	// The returned ObjectEventHandlerRegistration can be used to later remove the handler.
+	AddEventHandler(ctx context.Context, namespace, name string, handler cache.ResourceEventHandler) (ObjectEventHandlerRegistration, error)
+
+	// AddEventHandlerAsync is like AddEventHandler, but returns without waiting for the informer to sync.
+	// Informers of different objects sync concurrently. Once the Ready channel of the returned
+	// AsyncObjectEventHandlerRegistration is closed, Err reports whether the sync failed.
+	// A registration which failed to sync does not need to be removed.
+	AddEventHandlerAsync(ctx context.Context, namespace, name string, handler cache.ResourceEventHandler) (AsyncObjectEventHandlerRegistration, error)
+
+	// RemoveEventHandler removes a previously added event handler using the provided registration.
+	// If the handler is not found or if there is an issue removing it, an error is returned.
+	RemoveEventHandler(ObjectEventHandlerRegistration) error
+
+	// Get retrieves the object from the informer's cache using the provided ObjectEventHandlerRegistration.
+	// This allows accessing the latest state of the object without making an API call.
+	Get(ObjectEventHandlerRegistration) (T, error)
+
+	// Restart replaces the informer serving the provided registration with a fresh one, e.g. after
+	// credentials were rotated or the watch got stuck, and waits for it to sync until ctx is done.
+	// All handlers still registered with the informer are re-attached, so their registrations stay valid.
+	// The fresh informer runs as long as the one it replaces would have, regardless of ctx.
+	Restart(context.Context, ObjectEventHandlerRegistration) error
+
+	// Status returns the health of the informer monitoring the object with the given key.
+	Status(ObjectKey) (MonitorStatus, error)
+
+	// StatusAll returns the health of all informers of the monitor.
+	StatusAll() []MonitorStatus
+}
+
+// objectEventHandlerRegistration is an implementation of the ObjectEventHandlerRegistration.
+type objectEventHandlerRegistration struct {
+	// objectKey represents the unique identifier for the object associated with this event handler registration.
+	// It will be populated during AddEventHandler, and will be used during RemoveEventHandler, Get.
+	objectKey ObjectKey
+	// handler is the registered event handler. It is kept so that the handler
+	// can be re-attached when the object is moved to a different informer.
+	handler cache.ResourceEventHandler
+
+	lock sync.RWMutex
+	// registration is the handle of the informer the handler is currently attached to.
+	// For objects monitored by a namespaceMonitor, it is the handle of the namespaceMonitor.
+	registration cache.ResourceEventHandlerRegistration
+
+	// ready is closed by markReady once the informer has synced, or failed to sync
+	ready     chan struct{}
+	readyOnce sync.Once
+	syncErr   error
+	// prepare, if set, runs once the informer has synced, before the registration is marked ready.
+	// Its error is reported by Err.
+	prepare func() error
+
+	// deliverLock serializes the notifications of handler, which may come from two informers
+	// while the registration is moved from one to the other
+	deliverLock sync.Mutex
+	// last is the object last delivered to handler, nil if there is none or it was deleted
+	last interface{}
+	// moved is set while the informer the registration was moved to has not notified it yet
+	moved bool
+}
+
+func newObjectEventHandlerRegistration(key ObjectKey, handler cache.ResourceEventHandler) *objectEventHandlerRegistration {
+	return &objectEventHandlerRegistration{
+		objectKey: key,
+		handler:   handler,
+		ready:     make(chan struct{}),
+	}
+}
+
+func (r *objectEventHandlerRegistration) GetKey() ObjectKey {
+	return r.objectKey
+}
+
+func (r *objectEventHandlerRegistration) GetHandler() cache.ResourceEventHandlerRegistration {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.registration
+}
+
+func (r *objectEventHandlerRegistration) HasSynced() bool {
+	// not yet attached while waiting for a namespace-wide informer
+	registration := r.GetHandler()
+	return registration != nil && registration.HasSynced()
+}
+
+func (r *objectEventHandlerRegistration) Ready() <-chan struct{} {
+	return r.ready
+}
+
+func (r *objectEventHandlerRegistration) Err() error {
+	return r.syncErr
+}
+
+// markReady records the result of the informer sync and closes the ready channel, after running
+// prepare in the background if the sync succeeded. Only the first call has an effect.
+func (r *objectEventHandlerRegistration) markReady(err error) {
+	r.readyOnce.Do(func() {
+		if err != nil || r.prepare == nil {
+			r.syncErr = err
+			close(r.ready)
+			return
+		}
+		go func() {
+			r.syncErr = r.prepare()
+			close(r.ready)
+		}()
+	})
+}
+
+func (r *objectEventHandlerRegistration) setHandler(registration cache.ResourceEventHandlerRegistration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.registration = registration
+}
+
+// informerHandler returns the handler to add to an informer for the registration.
+func (r *objectEventHandlerRegistration) informerHandler() cache.ResourceEventHandler {
+	return registrationHandler{r}
+}
+
+// move marks the registration as being moved to another informer, which replays its cache to the handler.
+func (r *objectEventHandlerRegistration) move() {
+	r.deliverLock.Lock()
+	defer r.deliverLock.Unlock()
+	r.moved = true
+}
+
+// record remembers obj as the object last delivered to the handler, nil once deleted,
+// and ends a move. Only the object of a registration with a name is remembered.
+// Must be called with deliverLock held.
+func (r *objectEventHandlerRegistration) record(obj interface{}) {
+	r.moved = false
+	if r.objectKey.Name != "" {
+		r.last = obj
+	}
+}
+
+// registrationHandler delivers the notifications of an informer about the object of a registration
+// to its handler, one at a time. Other objects, e.g. of a namespace-wide informer, are skipped.
+// The informer a registration is moved to replays its cache as adds; the replayed add of an object
+// which was already delivered is dropped, or delivered as an update if the object changed meanwhile.
+type registrationHandler struct {
+	r *objectEventHandlerRegistration
+}
+
+// matches returns true if obj is the object of the registration. A registration
+// without name, e.g. of a namespace-wide informer, matches all objects.
+func (h registrationHandler) matches(obj interface{}) bool {
+	if h.r.objectKey.Name == "" {
+		return true
+	}
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		klog.Error(err)
+		return false
+	}
+	return key == h.r.objectKey.Namespace+"/"+h.r.objectKey.Name
+}
+
+func (h registrationHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	if !h.matches(obj) {
+		return
+	}
+	h.r.deliverLock.Lock()
+	defer h.r.deliverLock.Unlock()
+
+	last, moved := h.r.last, h.r.moved
+	h.r.record(obj)
+	if !moved || !isInInitialList || last == nil {
+		h.r.handler.OnAdd(obj, isInInitialList)
+		return
+	}
+	if sameObject(last, obj) {
+		return
+	}
+	h.r.handler.OnUpdate(last, obj)
+}
+
+// sameObject returns true if a and b have the same resourceVersion, or are equal if they have none.
+func sameObject(a, b interface{}) bool {
+	_, aVersion, _ := objectVersion(a)
+	_, bVersion, _ := objectVersion(b)
+	if aVersion != "" || bVersion != "" {
+		return aVersion == bVersion
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func (h registrationHandler) OnUpdate(oldObj, newObj interface{}) {
+	if !h.matches(newObj) {
+		return
+	}
+	h.r.deliverLock.Lock()
+	defer h.r.deliverLock.Unlock()
+
+	h.r.record(newObj)
+	h.r.handler.OnUpdate(oldObj, newObj)
+}
+
+func (h registrationHandler) OnDelete(obj interface{}) {
+	if !h.matches(obj) {
+		return
+	}
+	h.r.deliverLock.Lock()
+	defer h.r.deliverLock.Unlock()
+
+	h.r.record(nil)
+	h.r.handler.OnDelete(obj)
+}
+
+type monitoredItem struct {
+	itemMonitor *singleItemMonitor
+	numHandlers atomic.Int32
+	// registrations are the handler registrations served by itemMonitor
+	registrations map[*objectEventHandlerRegistration]struct{}
+	// synced is true once the first sync of itemMonitor has succeeded
+	synced bool
+}
+
+func newMonitoredItem(key ObjectKey, newInformer func() cache.SharedInformer) *monitoredItem {
+	return &monitoredItem{
+		itemMonitor:   newRestartableSingleItemMonitor(key, newInformer),
+		registrations: map[*objectEventHandlerRegistration]struct{}{},
+	}
+}
+
+// objectMonitor is an implementation of the ObjectMonitor
+type objectMonitor[T runtime.Object] struct {
+	// resource is used while reporting a missing object
+	resource schema.GroupResource
+	// createInformer creates a SharedInformer for monitoring a specific object
+	createInformer func(namespace, name string) cache.SharedInformer
+
+	// namespaceThreshold is the number of monitored object names in a namespace above which the
+	// per-object informers of that namespace are replaced by a single namespace-wide informer.
+	// Zero disables switching.
+	namespaceThreshold int
+	// createNamespaceInformer creates a SharedInformer for monitoring all objects of a namespace
+	createNamespaceInformer func(namespace string) cache.SharedInformer
+
+	// syncTimeout bounds the time spent waiting for an informer cache to sync
+	syncTimeout time.Duration
+
+	// transform is installed on every informer, to modify objects before they are cached. Optional.
+	transform cache.TransformFunc
+
+	lock       sync.RWMutex
+	monitors   map[ObjectKey]*monitoredItem
+	namespaces map[string]*namespaceMonitor
+	// switching are the namespaces being switched between per-object informers and a namespace-wide informer
+	switching map[string]*namespaceSwitch
+}
+
+// NewObjectMonitor returns an ObjectMonitor which lists and watches single objects of the given resource
+// using client. exampleObject is only used to determine the type of the monitored objects.
+func NewObjectMonitor[T runtime.Object](client cache.Getter, resource schema.GroupResource, exampleObject T) ObjectMonitor[T] {
+	return newObjectMonitor[T](resource, createSingleItemInformer(client, resource.Resource, exampleObject))
+}
+
+// NewConfigMapMonitor returns an ObjectMonitor for configmaps.
+func NewConfigMapMonitor(kubeClient kubernetes.Interface) ObjectMonitor[*corev1.ConfigMap] {
+	return NewObjectMonitor(kubeClient.CoreV1().RESTClient(), corev1.Resource("configmaps"), &corev1.ConfigMap{})
+}
+
+// NewServiceAccountMonitor returns an ObjectMonitor for serviceaccounts.
+func NewServiceAccountMonitor(kubeClient kubernetes.Interface) ObjectMonitor[*corev1.ServiceAccount] {
+	return NewObjectMonitor(kubeClient.CoreV1().RESTClient(), corev1.Resource("serviceaccounts"), &corev1.ServiceAccount{})
+}
+
+// NewServiceMonitor returns an ObjectMonitor for services.
+func NewServiceMonitor(kubeClient kubernetes.Interface) ObjectMonitor[*corev1.Service] {
+	return NewObjectMonitor(kubeClient.CoreV1().RESTClient(), corev1.Resource("services"), &corev1.Service{})
+}
+
+func newObjectMonitor[T runtime.Object](resource schema.GroupResource, createInformer func(namespace, name string) cache.SharedInformer) *objectMonitor[T] {
+	return &objectMonitor[T]{
+		resource:       resource,
+		createInformer: createInformer,
+		monitors:       map[ObjectKey]*monitoredItem{},
+		namespaces:     map[string]*namespaceMonitor{},
+		switching:      map[string]*namespaceSwitch{},
+		syncTimeout:    DefaultSyncTimeout,
+	}
+}
+
+// withNamespaceInformer enables switching a namespace to a single namespace-wide informer,
+// created by createNamespaceInformer, once more than threshold object names are monitored in it.
+func (o *objectMonitor[T]) withNamespaceInformer(threshold int, createNamespaceInformer func(namespace string) cache.SharedInformer) *objectMonitor[T] {
+	o.namespaceThreshold = threshold
+	o.createNamespaceInformer = createNamespaceInformer
+	return o
+}
+
+// withTransform installs transform on the informers created from now on.
+func (o *objectMonitor[T]) withTransform(transform cache.TransformFunc) *objectMonitor[T] {
+	o.transform = transform
+	return o
+}
+
+// transformed wraps newInformer, so that the created informers apply the transform of the monitor.
+func (o *objectMonitor[T]) transformed(newInformer func() cache.SharedInformer) func() cache.SharedInformer {
+	if o.transform == nil {
+		return newInformer
+	}
+	return func() cache.SharedInformer {
+		informer := newInformer()
+		if err := informer.SetTransform(o.transform); err != nil {
+			klog.Error(err)
+		}
+		return informer
+	}
+}
+
+// createSingleItemInformer returns a function which creates a SharedInformer
+// for monitoring a specific object of the given resource.
+func createSingleItemInformer(client cache.Getter, resource string, exampleObject runtime.Object) func(namespace, name string) cache.SharedInformer {
+	return func(namespace, name string) cache.SharedInformer {
+		return newObservedInformer(
+			cache.NewListWatchFromClient(
+				client,
+				resource,
+				namespace,
+				fields.OneTermEqualSelector("metadata.name", name),
+			),
+			exampleObject,
+		)
+	}
+}
+
+// AddEventHandler adds an event handler to the monitor.
+func (o *objectMonitor[T]) AddEventHandler(ctx context.Context, namespace, name string, handler cache.ResourceEventHandler) (ObjectEventHandlerRegistration, error) {
+	return o.addEventHandler(ctx, namespace, name, handler, func() cache.SharedInformer {
+		return o.createInformer(namespace, name)
+	}, nil)
+}
+
+// AddEventHandlerAsync adds an event handler to the monitor without waiting for the informer to sync.
+func (o *objectMonitor[T]) AddEventHandlerAsync(ctx context.Context, namespace, name string, handler cache.ResourceEventHandler) (AsyncObjectEventHandlerRegistration, error) {
+	return o.addEventHandlerAsync(ctx, namespace, name, handler, func() cache.SharedInformer {
+		return o.createInformer(namespace, name)
+	}, nil)
+}
+
+// addEventHandler adds an event handler, starts the informer if not already running,
+// and waits for the informer to sync and prepare, if not nil, to complete.
+func (o *objectMonitor[T]) addEventHandler(ctx context.Context, namespace, name string, handler cache.ResourceEventHandler, createInformerFn func() cache.SharedInformer, prepare func(ObjectEventHandlerRegistration) error) (ObjectEventHandlerRegistration, error) {
+	r, err := o.addEventHandlerAsync(ctx, namespace, name, handler, createInformerFn, prepare)
+	if err != nil {
+		return nil, err
+	}
+
+	// wait for first sync, bounded by syncTimeout
+	<-r.Ready()
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// addEventHandlerAsync adds an event handler and starts the informer if not already running.
+// The first sync of a new informer, or of the namespace-wide informer replacing the per-object
+// informers once the threshold is crossed, is awaited in the background, without holding the lock.
+// If not nil, prepare runs in the background once the informer has synced, and the registration
+// is only marked ready once it completes. The registration is removed if prepare fails.
+func (o *objectMonitor[T]) addEventHandlerAsync(ctx context.Context, namespace, name string, handler cache.ResourceEventHandler, createInformerFn func() cache.SharedInformer, prepare func(ObjectEventHandlerRegistration) error) (AsyncObjectEventHandlerRegistration, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	r, err := o.addEventHandlerLocked(ctx, namespace, name, handler, createInformerFn, prepare)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// addEventHandlerLocked is addEventHandlerAsync, for callers holding the lock.
+func (o *objectMonitor[T]) addEventHandlerLocked(ctx context.Context, namespace, name string, handler cache.ResourceEventHandler, createInformerFn func() cache.SharedInformer, prepare func(ObjectEventHandlerRegistration) error) (*objectEventHandlerRegistration, error) {
+	if handler == nil {
+		return nil, fmt.Errorf("nil handler is provided")
+	}
+
+	// object identifier (namespace/name)
+	key := NewObjectKey(namespace, name)
+	r := newObjectEventHandlerRegistration(key, handler)
+	if prepare != nil {
+		r.prepare = func() error {
+			err := prepare(r)
+			if err != nil {
+				if err := o.RemoveEventHandler(r); err != nil {
+					klog.Error(err)
+				}
+			}
+			return err
+		}
+	}
+
+	// namespace is already monitored by a namespace-wide informer
+	if n, exists := o.namespaces[namespace]; exists {
+		n.addHandler(r)
+		go n.join(r)
+		klog.Info(o.resource.String(), " handler added to namespace informer", " item key ", key)
+		return r, nil
+	}
+
+	// a new object waits for the namespace-wide informer being started, and is ready once it is swapped in
+	m, exists := o.monitors[key]
+	if sw, switching := o.switching[namespace]; switching && !exists {
+		sw.pending[r] = struct{}{}
+		klog.Info(o.resource.String(), " handler waiting for namespace informer", " item key ", key)
+		return r, nil
+	}
+
+	// switch to a namespace-wide informer in the background if this object crosses the threshold;
+	// the per-object informers started meanwhile are moved over once it is done
+	if !exists && o.namespaceThreshold > 0 && o.numMonitoredNames(namespace) >= o.namespaceThreshold {
+		sw := newNamespaceSwitch()
+		sw.pending[r] = struct{}{}
+		o.switching[namespace] = sw
+		go o.switchToNamespaceMonitor(namespace, sw)
+		klog.Info(o.resource.String(), " handler waiting for namespace informer", " item key ", key)
+		return r, nil
+	}
+
+	// start informer if monitor does not exists
+	if !exists {
+		m = newMonitoredItem(key, o.transformed(createInformerFn))
+		m.itemMonitor.start(ctx)
+
+		// add item key to monitors map // add watch to the list
+		o.monitors[key] = m
+		go o.waitForMonitoredItem(ctx, key, m)
+	}
+
+	// add the event handler
+	if err := m.itemMonitor.attach(r); err != nil {
+		return nil, err
+	}
+	m.registrations[r] = struct{}{}
+	m.numHandlers.Add(1)
+	if m.synced {
+		r.markReady(nil)
+	}
+	klog.Info(o.resource.String(), " handler added", " item key ", key)
+
+	return r, nil
+}
+
+// waitForMonitoredItem waits for the first sync of a monitoredItem started by addEventHandlerAsync,
+// and marks its registrations as ready. On failure the informer is stopped and removed.
+func (o *objectMonitor[T]) waitForMonitoredItem(ctx context.Context, key ObjectKey, m *monitoredItem) {
+	err := m.itemMonitor.WaitForSync(ctx, o.syncTimeout)
+	if err != nil {
+		m.itemMonitor.StopInformer()
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if err != nil {
+		// the monitor may already have been removed, or replaced by a namespace-wide informer
+		if o.monitors[key] == m {
+			delete(o.monitors, key)
+		}
+		klog.Error(o.resource.String(), " informer failed to sync", " item key ", key, " err ", err)
+	} else {
+		m.synced = true
+		klog.Info(o.resource.String(), " informer started", " item key ", key)
+	}
+
+	for r := range m.registrations {
+		r.markReady(err)
+	}
+}
+
+// startMonitoredItem starts the informer of a new monitoredItem and waits for its first sync,
+// bounded by ctx and syncTimeout. On failure the informer is stopped again.
+func startMonitoredItem(ctx context.Context, key ObjectKey, newInformer func() cache.SharedInformer, syncTimeout time.Duration) (*monitoredItem, error) {
+	m := newMonitoredItem(key, newInformer)
+	m.itemMonitor.start(ctx)
+
+	// wait for first sync
+	if err := m.itemMonitor.WaitForSync(ctx, syncTimeout); err != nil {
+		m.itemMonitor.StopInformer()
+		return nil, err
+	}
+	m.synced = true
+
+	return m, nil
+}
+
+// RemoveEventHandler removes an event handler and stops the informer if no handlers are left.
+// If the handler is not found or if there is an issue removing it, an error is returned.
+func (o *objectMonitor[T]) RemoveEventHandler(handlerRegistration ObjectEventHandlerRegistration) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if handlerRegistration == nil {
+		return fmt.Errorf("%s handler is nil", o.resource.String())
+	}
+
+	// Extract the key from the registration to identify the associated monitor.
+	// populated in AddEventHandler()
+	key := handlerRegistration.GetKey()
+
+	// check if informer already exists for the object(key)
+	m, exists := o.monitors[key]
+	if !exists {
+		if r, ok := handlerRegistration.(*objectEventHandlerRegistration); ok && o.removePendingHandler(r) {
+			return nil
+		}
+		return o.removeNamespaceHandler(handlerRegistration)
+	}
+
+	if err := m.itemMonitor.RemoveEventHandler(handlerRegistration); err != nil {
+		return err
+	}
+	if r, ok := handlerRegistration.(*objectEventHandlerRegistration); ok {
+		delete(m.registrations, r)
+		r.markReady(fmt.Errorf("%s handler removed before cache sync", o.resource.String()))
+	}
+	m.numHandlers.Add(-1)
+	klog.Info(o.resource.String(), " handler removed", " item key", key)
+
+	// stop informer if there is no handler
+	if m.numHandlers.Load() <= 0 {
+		if !m.itemMonitor.StopInformer() {
+			klog.Error(o.resource.String(), " informer already stopped", " item key", key)
+		}
+		// remove the key from map
+		delete(o.monitors, key)
+		klog.Info(o.resource.String(), " informer stopped", " item key ", key)
+	}
+
+	return nil
+}
+
+// Get retrieves the object from the informer's cache. Error if the object is not found in the cache.
+// The informer sync is awaited without holding the lock, so that other objects can be added,
+// removed and read meanwhile.
+func (o *objectMonitor[T]) Get(handlerRegistration ObjectEventHandlerRegistration) (T, error) {
+	var zero T
+	if handlerRegistration == nil {
+		return zero, fmt.Errorf("%s handler is nil", o.resource.String())
+	}
+	key := handlerRegistration.GetKey()
+
+	// check if informer exists
+	o.lock.RLock()
+	itemMonitor, _, exists := o.itemGetter(key)
+	o.lock.RUnlock()
+	if !exists {
+		return zero, fmt.Errorf("%s monitor doesn't exist for key %v", o.resource.String(), key)
+	}
+
+	// wait for informer store sync, to load objects
+	if err := waitForSync(context.Background(), key, o.syncTimeout, handlerRegistration.HasSynced, itemMonitor.LastError); err != nil {
+		return zero, err
+	}
+
+	// the object may have been moved to another informer meanwhile
+	o.lock.RLock()
+	_, getItem, exists := o.itemGetter(key)
+	o.lock.RUnlock()
+	if !exists {
+		return zero, fmt.Errorf("%s monitor doesn't exist for key %v", o.resource.String(), key)
+	}
+
+	uncast, exists, err := getItem()
+	if !exists {
+		return zero, apierrors.NewNotFound(o.resource, key.Name)
+	}
+
+	if err != nil {
+		return zero, err
+	}
+
+	obj, ok := uncast.(T)
+	if !ok {
+		return zero, fmt.Errorf("unexpected type: %T", uncast)
+	}
+
+	return obj, nil
+}
+
+// Restart restarts the informer which currently monitors the object of the registration,
+// and waits for the new informer to sync.
+func (o *objectMonitor[T]) Restart(ctx context.Context, handlerRegistration ObjectEventHandlerRegistration) error {
+	if handlerRegistration == nil {
+		return fmt.Errorf("%s handler is nil", o.resource.String())
+	}
+	key := handlerRegistration.GetKey()
+
+	o.lock.RLock()
+	itemMonitor, _, exists := o.itemGetter(key)
+	o.lock.RUnlock()
+	if !exists {
+		return fmt.Errorf("%s monitor doesn't exist for key %v", o.resource.String(), key)
+	}
+
+	if err := itemMonitor.Restart(); err != nil {
+		return err
+	}
+	klog.Info(o.resource.String(), " informer restarted", " item key ", key)
+
+	return itemMonitor.WaitForSync(ctx, o.syncTimeout)
+}
+
+// monitorsNamespace returns true if the namespace is monitored by a namespace-wide informer,
+// or is being switched to or from one.
+func (o *objectMonitor[T]) monitorsNamespace(namespace string) bool {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	_, monitored := o.namespaces[namespace]
+	_, switching := o.switching[namespace]
+	return monitored || switching
+}
+
+// itemGetter returns whichever informer currently monitors the object with the given key,
+// along with the function reading the object from its cache.
+func (o *objectMonitor[T]) itemGetter(key ObjectKey) (*singleItemMonitor, func() (interface{}, bool, error), bool) {
+	if m, exists := o.monitors[key]; exists {
+		return m.itemMonitor, m.itemMonitor.GetItem, true
+	}
+	if n, exists := o.namespaces[key.Namespace]; exists && n.hasName(key.Name) {
+		return n.itemMonitor, func() (interface{}, bool, error) { return n.getItem(key.Name) }, true
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,191 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+// fakeConfigMapInformer will list/watch only one configmap inside a namespace
+func fakeConfigMapInformer(ctx context.Context, fakeKubeClient *fake.Clientset) func(namespace, name string) cache.SharedInformer {
+	return func(namespace, name string) cache.SharedInformer {
+		fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+		return cache.NewSharedInformer(&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.FieldSelector = fieldSelector
+				return fakeKubeClient.CoreV1().ConfigMaps(namespace).List(ctx, options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.FieldSelector = fieldSelector
+				return fakeKubeClient.CoreV1().ConfigMaps(namespace).Watch(ctx, options)
+			},
+		},
+			&corev1.ConfigMap{},
+			0,
+		)
+	}
+}
+
+func fakeConfigMap(namespace, name string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Data: map[string]string{
+			"ca-bundle.crt": "test",
+		},
+	}
+}
+
+func TestObjectMonitor(t *testing.T) {
+	var (
+		namespace = "ns"
+		name      = "ca-bundle"
+		configMap = fakeConfigMap(namespace, name)
+	)
+
+	scenarios := []struct {
+		name            string
+		withConfigMap   bool
+		numAddition     int
+		numRemoval      int
+		expectConfigMap *corev1.ConfigMap
+		expectGetErr    bool
+		expectKeyExist  bool
+	}{
+		{
+			name:            "configmap exists and handlers are still registered",
+			withConfigMap:   true,
+			numAddition:     3,
+			numRemoval:      2,
+			expectConfigMap: configMap,
+			expectKeyExist:  true,
+		},
+		{
+			name:            "configmap does not exist in cluster",
+			withConfigMap:   false,
+			numAddition:     1,
+			numRemoval:      0,
+			expectConfigMap: nil,
+			expectGetErr:    true,
+			expectKeyExist:  true,
+		},
+		{
+			name:            "all handlers are removed",
+			withConfigMap:   true,
+			numAddition:     2,
+			numRemoval:      2,
+			expectConfigMap: nil,
+			expectGetErr:    true,
+			expectKeyExist:  false,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			var fakeKubeClient *fake.Clientset
+			if s.withConfigMap {
+				fakeKubeClient = fake.NewSimpleClientset(configMap)
+			} else {
+				fakeKubeClient = fake.NewSimpleClientset()
+			}
+			key := NewObjectKey(namespace, name)
+			om := newObjectMonitor[*corev1.ConfigMap](corev1.Resource("configmaps"), fakeConfigMapInformer(context.TODO(), fakeKubeClient))
+
+			handlers := []ObjectEventHandlerRegistration{}
+			for i := 0; i < s.numAddition; i++ {
+				h, err := om.AddEventHandler(context.TODO(), namespace, name, cache.ResourceEventHandlerFuncs{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				handlers = append(handlers, h)
+			}
+			for i := 0; i < s.numRemoval; i++ {
+				if err := om.RemoveEventHandler(handlers[i]); err != nil {
+					t.Error(err)
+				}
+			}
+
+			if _, exist := om.monitors[key]; exist != s.expectKeyExist {
+				t.Errorf("expected %t, got %t", s.expectKeyExist, exist)
+			}
+
+			gotConfigMap, gotErr := om.Get(handlers[len(handlers)-1])
+			if gotErr != nil && !s.expectGetErr {
+				t.Errorf("unexpected error %v", gotErr)
+			}
+			if gotErr == nil && s.expectGetErr {
+				t.Errorf("expecting an error, got nil")
+			}
+			if !reflect.DeepEqual(s.expectConfigMap, gotConfigMap) {
+				t.Errorf("expected %v got %v", s.expectConfigMap, gotConfigMap)
+			}
+		})
+	}
+}
+
+func TestAddEventHandlerSyncFailure(t *testing.T) {
+	var (
+		namespace = "ns"
+		name      = "ca-bundle"
+	)
+
+	scenarios := []struct {
+		name         string
+		listErr      error
+		expectReason SyncFailureReason
+	}{
+		{
+			name:         "list is forbidden",
+			listErr:      apierrors.NewForbidden(corev1.Resource("configmaps"), "", fmt.Errorf("denied")),
+			expectReason: SyncForbidden,
+		},
+		{
+			name:         "list keeps failing until the deadline",
+			listErr:      apierrors.NewInternalError(fmt.Errorf("etcd is down")),
+			expectReason: SyncTimeout,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			fakeKubeClient := fake.NewSimpleClientset()
+			fakeKubeClient.PrependReactor("list", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				return true, nil, s.listErr
+			})
+			om := newObjectMonitor[*corev1.ConfigMap](corev1.Resource("configmaps"), fakeConfigMapInformer(context.TODO(), fakeKubeClient))
+			om.syncTimeout = 500 * time.Millisecond
+
+			h, err := om.AddEventHandler(context.TODO(), namespace, name, cache.ResourceEventHandlerFuncs{})
+			if err == nil {
+				t.Fatal("expecting an error, got nil")
+			}
+			if h != nil {
+				t.Errorf("expected nil registration, got %v", h)
+			}
+			if reason := ReasonForSyncError(err); reason != s.expectReason {
+				t.Errorf("expected reason %s, got %s (%v)", s.expectReason, reason, err)
+			}
+			if len(om.monitors) != 0 {
+				t.Error("monitor should not be added into map")
+			}
+		})
+	}
+}
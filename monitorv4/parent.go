@@ -0,0 +1,53 @@
+package secret
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// routeGroupKind is the GroupKind of the routes registered through the route specific Manager methods.
+var routeGroupKind = schema.GroupKind{Group: "route.openshift.io", Kind: "Route"}
+
+// ParentKey identifies an object referencing secrets, e.g. a Route, Ingress, Pod or custom resource.
+// Parents of different kinds with the same name don't collide, and a parent which was deleted and
+// recreated with the same name gets a new key through its UID.
+type ParentKey struct {
+	// GroupKind is the group and kind of the parent.
+	GroupKind schema.GroupKind
+	// Namespace is the namespace of the parent.
+	Namespace string
+	// Name is metadata.name of the parent.
+	Name string
+	// UID is metadata.uid of the parent. Empty if unknown.
+	UID types.UID
+}
+
+// NewParentKey creates a new ParentKey for the given parent object of kind groupKind.
+func NewParentKey(groupKind schema.GroupKind, parent metav1.Object) ParentKey {
+	return ParentKey{
+		GroupKind: groupKind,
+		Namespace: parent.GetNamespace(),
+		Name:      parent.GetName(),
+		UID:       parent.GetUID(),
+	}
+}
+
+// NewRouteKey creates the ParentKey under which the route specific Manager methods register the route
+// with the given namespace and name. It has no UID.
+func NewRouteKey(namespace, routeName string) ParentKey {
+	return ParentKey{
+		GroupKind: routeGroupKind,
+		Namespace: namespace,
+		Name:      routeName,
+	}
+}
+
+func (k ParentKey) String() string {
+	if k.UID == "" {
+		return fmt.Sprintf("%s %s/%s", k.GroupKind, k.Namespace, k.Name)
+	}
+	return fmt.Sprintf("%s %s/%s (%s)", k.GroupKind, k.Namespace, k.Name, k.UID)
+}
@@ -0,0 +1,198 @@
+package secret
+
+import (
+	"context"
+	"sort"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// indexParent records that the parent references the secret. The lock must be held.
+func (m *Manager) indexParent(parent ParentKey, secret ObjectKey) {
+	if _, exists := m.parents[secret]; !exists {
+		m.parents[secret] = map[ParentKey]struct{}{}
+	}
+	m.parents[secret][parent] = struct{}{}
+}
+
+// unindexParent records that the parent no longer references the secret. The lock must be held.
+func (m *Manager) unindexParent(parent ParentKey, secret ObjectKey) {
+	delete(m.parents[secret], parent)
+	if len(m.parents[secret]) == 0 {
+		delete(m.parents, secret)
+	}
+}
+
+// reindexParent indexes the parent for exactly the secrets of its registrations. The lock must be held.
+func (m *Manager) reindexParent(parent ParentKey, registrations map[ObjectKey]SecretEventHandlerRegistration) {
+	for secret, parents := range m.parents {
+		if _, exists := parents[parent]; !exists {
+			continue
+		}
+		if _, exists := registrations[secret]; !exists {
+			m.unindexParent(parent, secret)
+		}
+	}
+	for secret := range registrations {
+		m.indexParent(parent, secret)
+	}
+}
+
+// ParentsFor returns the keys of all registered parents referencing the secret, sorted.
+func (m *Manager) ParentsFor(secret ObjectKey) []ParentKey {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	parents := make([]ParentKey, 0, len(m.parents[secret]))
+	for parent := range m.parents[secret] {
+		parents = append(parents, parent)
+	}
+	sort.Slice(parents, func(i, j int) bool {
+		return lessParentKey(parents[i], parents[j])
+	})
+	return parents
+}
+
+// lessParentKey orders parent keys by group, kind, namespace, name and UID.
+func lessParentKey(a, b ParentKey) bool {
+	switch {
+	case a.GroupKind.Group != b.GroupKind.Group:
+		return a.GroupKind.Group < b.GroupKind.Group
+	case a.GroupKind.Kind != b.GroupKind.Kind:
+		return a.GroupKind.Kind < b.GroupKind.Kind
+	case a.Namespace != b.Namespace:
+		return a.Namespace < b.Namespace
+	case a.Name != b.Name:
+		return a.Name < b.Name
+	}
+	return a.UID < b.UID
+}
+
+// EnqueueParentsHandler returns the built-in secret handler, which adds the ParentKey of every parent
+// referencing the secret to the Manager's queue whenever the secret changes. Since it looks up the
+// parents in the reverse index, one handler can be registered for all parents, and does not need to
+// capture any of them. The Manager adds it to the informer once per secret rather than once per parent,
+// so that each parent is enqueued once per event. Parents referencing a secret in another namespace
+// are enqueued by their own registration, which is subject to their grants.
+func (m *Manager) EnqueueParentsHandler() cache.ResourceEventHandler {
+	return parentEnqueuer{manager: m}
+}
+
+// parentEnqueuer is the built-in secret handler of the Manager.
+type parentEnqueuer struct {
+	manager *Manager
+	// parent, if set, is the only parent enqueued, which references the secret from another namespace
+	parent *ParentKey
+}
+
+// sharedEnqueuer is the registration of the built-in handler shared by the parents of a secret.
+type sharedEnqueuer struct {
+	registration SecretEventHandlerRegistration
+	// coalescer merges the bursts of events of the secret, if a coalescing window is configured
+	coalescer *coalescingHandler
+	refs      int
+}
+
+// acquireEnqueuer adds the built-in handler for the secret, unless already added for another parent
+// in the secret's namespace, in which case the parent is enqueued if the secret exists, as the initial
+// list of a registration of its own would. The informer sync is awaited without holding the lock.
+func (m *Manager) acquireEnqueuer(ctx context.Context, parent ParentKey, secret ObjectKey) error {
+	m.enqueuersLock.Lock()
+	if enqueuer, exists := m.enqueuers[secret]; exists {
+		enqueuer.refs++
+		m.enqueuersLock.Unlock()
+		if _, err := m.monitor.GetSecret(enqueuer.registration); err == nil {
+			m.resourceChanges.Add(parent)
+		}
+		return nil
+	}
+	m.enqueuersLock.Unlock()
+
+	enqueuer := &sharedEnqueuer{refs: 1}
+	var handler cache.ResourceEventHandler = parentEnqueuer{manager: m}
+	if m.coalesceWindow > 0 {
+		enqueuer.coalescer = newCoalescingHandler(handler, m.coalesceWindow)
+		handler = enqueuer.coalescer
+	}
+	// the handler reads no data of the secret
+	handlerRegistration, err := m.monitor.AddSecretDataEventHandler(ctx, secret.Namespace, secret.Name, []string{}, handler)
+	if err != nil {
+		if enqueuer.coalescer != nil {
+			enqueuer.coalescer.stop()
+		}
+		return err
+	}
+	enqueuer.registration = handlerRegistration
+
+	m.enqueuersLock.Lock()
+	defer m.enqueuersLock.Unlock()
+	// added for another parent meanwhile
+	if existing, exists := m.enqueuers[secret]; exists {
+		existing.refs++
+		enqueuer.stop(m)
+		return nil
+	}
+	m.enqueuers[secret] = enqueuer
+	return nil
+}
+
+// releaseEnqueuer removes the built-in handler for the secret once no parent uses it anymore.
+func (m *Manager) releaseEnqueuer(secret ObjectKey) {
+	m.enqueuersLock.Lock()
+	defer m.enqueuersLock.Unlock()
+
+	enqueuer, exists := m.enqueuers[secret]
+	if !exists {
+		return
+	}
+	if enqueuer.refs--; enqueuer.refs <= 0 {
+		enqueuer.stop(m)
+		delete(m.enqueuers, secret)
+	}
+}
+
+// stop removes the handler from the informer, and drops the events waiting for the coalescing window to pass.
+func (e *sharedEnqueuer) stop(m *Manager) {
+	if err := m.monitor.RemoveSecretEventHandler(e.registration); err != nil {
+		klog.Error(err)
+	}
+	if e.coalescer != nil {
+		e.coalescer.stop()
+	}
+}
+
+func (e parentEnqueuer) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+	if e.parent != nil {
+		e.manager.resourceChanges.Add(*e.parent)
+		return
+	}
+	for _, parent := range e.manager.ParentsFor(NewObjectKey(namespace, name)) {
+		// parents in other namespaces are enqueued by their own registration
+		if parent.Namespace == namespace {
+			e.manager.resourceChanges.Add(parent)
+		}
+	}
+}
+
+func (e parentEnqueuer) OnAdd(obj interface{}, isInInitialList bool) {
+	e.enqueue(obj)
+}
+
+func (e parentEnqueuer) OnUpdate(oldObj, newObj interface{}) {
+	e.enqueue(newObj)
+}
+
+func (e parentEnqueuer) OnDelete(obj interface{}) {
+	e.enqueue(obj)
+}
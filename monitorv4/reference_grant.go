@@ -0,0 +1,248 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// ReferenceGrantGVR is the resource of the grant policy objects gating cross-namespace secret references.
+// A grant in the namespace of a secret allows parents of the kinds and namespaces listed in spec.from
+// to reference the secrets listed in spec.to, like a Gateway API ReferenceGrant.
+var ReferenceGrantGVR = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1beta1", Resource: "referencegrants"}
+
+// referenceGrants monitors the grants of the namespaces with referenced secrets.
+type referenceGrants struct {
+	selectors *selectorMonitor[*unstructured.Unstructured]
+}
+
+func newReferenceGrants(dynamicClient dynamic.Interface, gvr schema.GroupVersionResource) *referenceGrants {
+	return &referenceGrants{
+		selectors: newSelectorMonitor[*unstructured.Unstructured](gvr.GroupResource(), createDynamicSelectorInformer(dynamicClient, gvr)),
+	}
+}
+
+// createDynamicSelectorInformer returns a function which creates a dynamic SharedInformer
+// for monitoring the objects of the given resource matching a label selector.
+func createDynamicSelectorInformer(dynamicClient dynamic.Interface, gvr schema.GroupVersionResource) func(namespace string, selector labels.Selector) cache.SharedInformer {
+	return func(namespace string, selector labels.Selector) cache.SharedInformer {
+		return dynamicinformer.NewFilteredDynamicInformer(
+			dynamicClient,
+			gvr,
+			namespace,
+			0,
+			cache.Indexers{},
+			func(options *metav1.ListOptions) {
+				options.LabelSelector = selector.String()
+			},
+		).Informer()
+	}
+}
+
+// grantAllows returns true if grant allows parent to reference secret.
+func grantAllows(grant *unstructured.Unstructured, parent ParentKey, secret ObjectKey) bool {
+	if grant.GetNamespace() != secret.Namespace {
+		return false
+	}
+
+	from, _, _ := unstructured.NestedSlice(grant.Object, "spec", "from")
+	fromAllowed := false
+	for _, f := range from {
+		f, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if f["group"] == parent.GroupKind.Group && f["kind"] == parent.GroupKind.Kind && f["namespace"] == parent.Namespace {
+			fromAllowed = true
+			break
+		}
+	}
+	if !fromAllowed {
+		return false
+	}
+
+	to, _, _ := unstructured.NestedSlice(grant.Object, "spec", "to")
+	for _, t := range to {
+		t, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// an empty name grants access to all secrets of the namespace
+		name, _ := t["name"].(string)
+		if t["group"] == "" && t["kind"] == "Secret" && (name == "" || name == secret.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// crossNamespaceReference is a reference of a parent to a secret in another namespace.
+// It forwards the secret events to the parent's handler only while a grant allows the reference,
+// and notifies the handler about the loss of the secret once the grant is revoked.
+type crossNamespaceReference struct {
+	parent  ParentKey
+	secret  ObjectKey
+	handler cache.ResourceEventHandler
+	grants  *referenceGrants
+
+	lock    sync.RWMutex
+	granted bool
+	// secretRegistration is the registration of the reference on the secret monitor
+	secretRegistration SecretEventHandlerRegistration
+	// getSecret reads the secret from the cache of the secret monitor
+	getSecret func(SecretEventHandlerRegistration) (*corev1.Secret, error)
+	// grantRegistration is the registration of the reference on the grants of the secret's namespace
+	grantRegistration SelectorEventHandlerRegistration
+}
+
+// watchGrants starts watching the grants of the secret's namespace,
+// and returns whether any of them allows the reference.
+func (r *crossNamespaceReference) watchGrants(ctx context.Context) (bool, error) {
+	registration, err := r.grants.selectors.AddEventHandler(ctx, r.secret.Namespace, labels.Everything(), grantEventHandler{r})
+	if err != nil {
+		return false, err
+	}
+	<-registration.Ready()
+	if err := registration.Err(); err != nil {
+		return false, err
+	}
+	r.grantRegistration = registration
+
+	allowed, err := r.allowed()
+	if err != nil {
+		r.stopWatchingGrants()
+		return false, err
+	}
+	r.lock.Lock()
+	r.granted = allowed
+	r.lock.Unlock()
+	return allowed, nil
+}
+
+// stopWatchingGrants removes the reference from the grants of the secret's namespace.
+func (r *crossNamespaceReference) stopWatchingGrants() {
+	if r.grantRegistration == nil {
+		return
+	}
+	if err := r.grants.selectors.RemoveEventHandler(r.grantRegistration); err != nil {
+		klog.Error(err)
+	}
+}
+
+// allowed returns true if a grant of the secret's namespace allows the reference.
+func (r *crossNamespaceReference) allowed() (bool, error) {
+	grants, err := r.grants.selectors.List(r.grantRegistration)
+	if err != nil {
+		return false, err
+	}
+	for _, grant := range grants {
+		if grantAllows(grant, r.parent, r.secret) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isGranted returns true if the reference is currently allowed.
+func (r *crossNamespaceReference) isGranted() bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.granted
+}
+
+// forbidden returns the error reported while the reference is not allowed.
+func (r *crossNamespaceReference) forbidden() error {
+	return apierrors.NewForbidden(corev1.Resource("secrets"), r.secret.Name, errNotGranted(r.parent, r.secret))
+}
+
+// regrant re-evaluates the grants, and notifies the handler when the reference is revoked or granted again.
+func (r *crossNamespaceReference) regrant() {
+	allowed, err := r.allowed()
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	r.lock.Lock()
+	changed := r.granted != allowed
+	r.granted = allowed
+	registration := r.secretRegistration
+	r.lock.Unlock()
+	if !changed || registration == nil {
+		return
+	}
+
+	secret, err := r.getSecret(registration)
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Error(err)
+		return
+	}
+	switch {
+	case !allowed && secret != nil:
+		klog.Info("reference grant revoked for ", r.parent, " secret ", r.secret)
+		r.handler.OnDelete(secret)
+	case !allowed:
+		klog.Info("reference grant revoked for ", r.parent, " secret ", r.secret)
+		r.handler.OnDelete(cache.DeletedFinalStateUnknown{Key: r.secret.Namespace + "/" + r.secret.Name})
+	case secret != nil:
+		klog.Info("reference granted for ", r.parent, " secret ", r.secret)
+		r.handler.OnAdd(secret, false)
+	}
+}
+
+// OnAdd forwards the secret add notification while the reference is allowed.
+func (r *crossNamespaceReference) OnAdd(obj interface{}, isInInitialList bool) {
+	if r.isGranted() {
+		r.handler.OnAdd(obj, isInInitialList)
+	}
+}
+
+// OnUpdate forwards the secret update notification while the reference is allowed.
+func (r *crossNamespaceReference) OnUpdate(oldObj, newObj interface{}) {
+	if r.isGranted() {
+		r.handler.OnUpdate(oldObj, newObj)
+	}
+}
+
+// OnDelete forwards the secret delete notification while the reference is allowed.
+func (r *crossNamespaceReference) OnDelete(obj interface{}) {
+	if r.isGranted() {
+		r.handler.OnDelete(obj)
+	}
+}
+
+// grantEventHandler re-evaluates the reference on every change of the grants of the secret's namespace.
+type grantEventHandler struct {
+	reference *crossNamespaceReference
+}
+
+func (h grantEventHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	// the initial grants are evaluated by watchGrants
+	if !isInInitialList {
+		h.reference.regrant()
+	}
+}
+
+func (h grantEventHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.reference.regrant()
+}
+
+func (h grantEventHandler) OnDelete(obj interface{}) {
+	h.reference.regrant()
+}
+
+// errNotGranted is the reason of the error reported for a reference which no grant allows.
+func errNotGranted(parent ParentKey, secret ObjectKey) error {
+	return fmt.Errorf("no reference grant in namespace %s allows %v to reference secret %s", secret.Namespace, parent, secret.Name)
+}
@@ -0,0 +1,203 @@
+package secret
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func fakeReferenceGrant(namespace, name, fromNamespace, toName string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "gateway.networking.k8s.io/v1beta1",
+			"kind":       "ReferenceGrant",
+			"metadata": map[string]interface{}{
+				"namespace": namespace,
+				"name":      name,
+			},
+			"spec": map[string]interface{}{
+				"from": []interface{}{
+					map[string]interface{}{
+						"group":     routeGroupKind.Group,
+						"kind":      routeGroupKind.Kind,
+						"namespace": fromNamespace,
+					},
+				},
+				"to": []interface{}{
+					map[string]interface{}{
+						"group": "",
+						"kind":  "Secret",
+						"name":  toName,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestGrantAllows(t *testing.T) {
+	var (
+		parent = NewRouteKey("app", "route")
+		secret = NewObjectKey("certs", "tls")
+	)
+
+	scenarios := []struct {
+		name        string
+		grant       *unstructured.Unstructured
+		expectAllow bool
+	}{
+		{
+			name:        "grant allows the secret",
+			grant:       fakeReferenceGrant("certs", "grant", "app", "tls"),
+			expectAllow: true,
+		},
+		{
+			name:        "grant allows all secrets of the namespace",
+			grant:       fakeReferenceGrant("certs", "grant", "app", ""),
+			expectAllow: true,
+		},
+		{
+			name:  "grant allows another secret",
+			grant: fakeReferenceGrant("certs", "grant", "app", "other"),
+		},
+		{
+			name:  "grant allows another namespace",
+			grant: fakeReferenceGrant("certs", "grant", "other", "tls"),
+		},
+		{
+			name:  "grant lives in another namespace",
+			grant: fakeReferenceGrant("app", "grant", "app", "tls"),
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if allowed := grantAllows(s.grant, parent, secret); allowed != s.expectAllow {
+				t.Errorf("expected allowed %t, got %t", s.expectAllow, allowed)
+			}
+		})
+	}
+
+	ingress := ParentKey{GroupKind: schema.GroupKind{Group: "networking.k8s.io", Kind: "Ingress"}, Namespace: "app", Name: "route"}
+	if grantAllows(fakeReferenceGrant("certs", "grant", "app", "tls"), ingress, secret) {
+		t.Error("grant for routes should not allow ingresses")
+	}
+}
+
+func TestManagerCrossNamespaceReference(t *testing.T) {
+	var (
+		parent = NewRouteKey("app", "route")
+		secret = NewObjectKey("certs", "tls")
+	)
+
+	scenarios := []struct {
+		name             string
+		withGrants       bool
+		grant            *unstructured.Unstructured
+		expectRegistered bool
+	}{
+		{
+			name:       "cross-namespace references are not enabled",
+			withGrants: false,
+			grant:      fakeReferenceGrant("certs", "grant", "app", "tls"),
+		},
+		{
+			name:       "no grant allows the reference",
+			withGrants: true,
+			grant:      fakeReferenceGrant("certs", "grant", "other", "tls"),
+		},
+		{
+			name:             "grant allows the reference until it is revoked",
+			withGrants:       true,
+			grant:            fakeReferenceGrant("certs", "grant", "app", "tls"),
+			expectRegistered: true,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			fakeKubeClient := fake.NewSimpleClientset(fakeSecret(secret.Namespace, secret.Name))
+			fakeDynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+				runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{ReferenceGrantGVR: "ReferenceGrantList"},
+				s.grant.DeepCopy(),
+			)
+			m := fakeManager(fakeKubeClient)
+			if s.withGrants {
+				m.grants = newReferenceGrants(fakeDynamicClient, ReferenceGrantGVR)
+			}
+
+			var numAdd, numDelete atomic.Int32
+			handler := cache.ResourceEventHandlerFuncs{
+				AddFunc:    func(obj interface{}) { numAdd.Add(1) },
+				DeleteFunc: func(obj interface{}) { numDelete.Add(1) },
+			}
+			err := m.RegisterParentReferences(context.TODO(), parent, []ObjectKey{secret}, handler)
+			if !s.expectRegistered {
+				if !apierrors.IsForbidden(err) {
+					t.Fatalf("expected forbidden error, got %v", err)
+				}
+				if len(m.registeredHandlers) != 0 || len(m.monitor.(*secretMonitor).monitors) != 0 {
+					t.Error("forbidden reference should not be watched")
+				}
+				if m.grants != nil && len(m.grants.selectors.monitors) != 0 {
+					t.Error("grants should not be watched")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := m.GetReferencedSecret(parent, secret); err != nil {
+				t.Fatal(err)
+			}
+
+			poll := func(condition func() bool) error {
+				return wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 2*time.Second, true, func(context.Context) (bool, error) {
+					return condition(), nil
+				})
+			}
+
+			// revoking the grant is reported as a secret loss
+			grants := fakeDynamicClient.Resource(ReferenceGrantGVR).Namespace(secret.Namespace)
+			if err := grants.Delete(context.TODO(), s.grant.GetName(), metav1.DeleteOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if err := poll(func() bool { return numDelete.Load() == 1 }); err != nil {
+				t.Fatalf("expected delete event after revocation, got %d", numDelete.Load())
+			}
+			if _, err := m.GetReferencedSecret(parent, secret); !apierrors.IsForbidden(err) {
+				t.Errorf("expected forbidden error after revocation, got %v", err)
+			}
+
+			// granting again restores the secret
+			if _, err := grants.Create(context.TODO(), s.grant.DeepCopy(), metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if err := poll(func() bool { return numAdd.Load() == 2 }); err != nil {
+				t.Fatalf("expected add event after grant, got %d", numAdd.Load())
+			}
+			if _, err := m.GetReferencedSecret(parent, secret); err != nil {
+				t.Error(err)
+			}
+
+			if err := m.UnregisterParent(parent); err != nil {
+				t.Error(err)
+			}
+			if len(m.grants.selectors.monitors) != 0 || len(m.references) != 0 {
+				t.Error("grants should not be watched after unregistering")
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package secret
+
+import (
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// secretDataKeys tracks which data keys the registrations of each secret need,
+// and strips all other data keys from the secrets before they are cached.
+type secretDataKeys struct {
+	lock sync.RWMutex
+	// needed is the set of needed data keys, keyed by secret
+	needed map[ObjectKey]*neededDataKeys
+	// registrations are the data keys needed by each registration, nil if it needs all of them
+	registrations map[ObjectEventHandlerRegistration][]string
+}
+
+// neededDataKeys counts the registrations needing each data key of a secret.
+type neededDataKeys struct {
+	// all is the number of registrations which need all data keys
+	all  int
+	keys map[string]int
+}
+
+func newSecretDataKeys() *secretDataKeys {
+	return &secretDataKeys{
+		needed:        map[ObjectKey]*neededDataKeys{},
+		registrations: map[ObjectEventHandlerRegistration][]string{},
+	}
+}
+
+// registered returns true if any registration of the secret needs data keys.
+func (d *secretDataKeys) registered(key ObjectKey) bool {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	_, exists := d.needed[key]
+	return exists
+}
+
+// covers returns true if the data keys of the secret which are cached include dataKeys.
+// A secret without any registrations is cached without data.
+func (d *secretDataKeys) covers(key ObjectKey, dataKeys []string) bool {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	n, exists := d.needed[key]
+	if !exists {
+		return false
+	}
+	if n.all > 0 {
+		return true
+	}
+	if dataKeys == nil {
+		return false
+	}
+	for _, dataKey := range dataKeys {
+		if n.keys[dataKey] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// acquire records that a registration of the secret needs dataKeys. Nil dataKeys means all keys.
+// It is called before the handler is added, so that the first list of a new informer is already stripped.
+func (d *secretDataKeys) acquire(key ObjectKey, dataKeys []string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	n, exists := d.needed[key]
+	if !exists {
+		n = &neededDataKeys{keys: map[string]int{}}
+		d.needed[key] = n
+	}
+	if dataKeys == nil {
+		n.all++
+	}
+	for _, dataKey := range dataKeys {
+		n.keys[dataKey]++
+	}
+}
+
+// release undoes acquire.
+func (d *secretDataKeys) release(key ObjectKey, dataKeys []string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.releaseLocked(key, dataKeys)
+}
+
+func (d *secretDataKeys) releaseLocked(key ObjectKey, dataKeys []string) {
+	n, exists := d.needed[key]
+	if !exists {
+		return
+	}
+	if dataKeys == nil {
+		n.all--
+	}
+	for _, dataKey := range dataKeys {
+		if n.keys[dataKey]--; n.keys[dataKey] <= 0 {
+			delete(n.keys, dataKey)
+		}
+	}
+	if n.all <= 0 && len(n.keys) == 0 {
+		delete(d.needed, key)
+	}
+}
+
+// bind remembers the data keys acquired for a registration, so that remove can release them.
+func (d *secretDataKeys) bind(r ObjectEventHandlerRegistration, dataKeys []string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.registrations[r] = dataKeys
+}
+
+// remove releases the data keys of a registration. It is a no-op for unknown registrations.
+func (d *secretDataKeys) remove(r ObjectEventHandlerRegistration) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	dataKeys, exists := d.registrations[r]
+	if !exists {
+		return
+	}
+	delete(d.registrations, r)
+	d.releaseLocked(r.GetKey(), dataKeys)
+}
+
+// transform strips the data keys which no registration of the secret needs.
+// Secrets without registrations, e.g. other secrets cached by a namespace-wide informer, are stripped
+// of all data.
+func (d *secretDataKeys) transform(obj interface{}) (interface{}, error) {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return obj, nil
+	}
+
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	n, exists := d.needed[NewObjectKey(secret.Namespace, secret.Name)]
+	if !exists {
+		secret.Data = nil
+		return secret, nil
+	}
+	if n.all > 0 {
+		return obj, nil
+	}
+	for dataKey := range secret.Data {
+		if n.keys[dataKey] == 0 {
+			delete(secret.Data, dataKey)
+		}
+	}
+	return secret, nil
+}
@@ -3,25 +3,30 @@ package secret
 import (
 	"context"
 	"fmt"
-	"sync"
-	"sync/atomic"
 
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 )
 
 // SecretEventHandlerRegistration is for registering and unregistering event handlers for secret monitoring.
-type SecretEventHandlerRegistration interface {
-	cache.ResourceEventHandlerRegistration
+type SecretEventHandlerRegistration = ObjectEventHandlerRegistration
 
-	GetKey() ObjectKey
-	GetHandler() cache.ResourceEventHandlerRegistration
-}
+// AsyncSecretEventHandlerRegistration is a SecretEventHandlerRegistration whose informer may still be syncing.
+type AsyncSecretEventHandlerRegistration = AsyncObjectEventHandlerRegistration
+
+// SecretSelectorEventHandlerRegistration is for registering and unregistering event handlers
+// for monitoring secrets matching a label selector.
+type SecretSelectorEventHandlerRegistration = SelectorEventHandlerRegistration
 
 // SecretMonitor helps in monitoring and handling a specific secret using singleItemMonitor.
 type SecretMonitor interface {
@@ -30,6 +35,17 @@ type SecretMonitor interface {
 	// The returned SecretEventHandlerRegistration can be used to later remove the handler.
 	AddSecretEventHandler(ctx context.Context, namespace, secretName string, handler cache.ResourceEventHandler) (SecretEventHandlerRegistration, error)
 
+	// AddSecretEventHandlerAsync is like AddSecretEventHandler, but returns without waiting for the informer
+	// to sync, so that informers of different secrets sync concurrently. The Ready channel of the returned
+	// AsyncSecretEventHandlerRegistration is closed once the secret is cached with all its data keys,
+	// re-reading it if needed. Err then reports whether the sync failed.
+	AddSecretEventHandlerAsync(ctx context.Context, namespace, secretName string, handler cache.ResourceEventHandler) (AsyncSecretEventHandlerRegistration, error)
+
+	// AddSecretDataEventHandler is like AddSecretEventHandler, but declares the data keys the handler needs.
+	// All other data keys are stripped from the secret before it is cached, unless another registration
+	// of the same secret needs them. The secret is re-read if it was cached with fewer keys than needed.
+	AddSecretDataEventHandler(ctx context.Context, namespace, secretName string, dataKeys []string, handler cache.ResourceEventHandler) (SecretEventHandlerRegistration, error)
+
 	// RemoveSecretEventHandler removes a previously added secret event handler using the provided registration.
 	// If the handler is not found or if there is an issue removing it, an error is returned.
 	RemoveSecretEventHandler(SecretEventHandlerRegistration) error
@@ -37,181 +53,339 @@ type SecretMonitor interface {
 	// GetSecret retrieves the secret object from the informer's cache using the provided SecretEventHandlerRegistration.
 	// This allows accessing the latest state of the secret without making an API call.
 	GetSecret(SecretEventHandlerRegistration) (*v1.Secret, error)
-}
 
-// secretEventHandlerRegistration is an implementation of the SecretEventHandlerRegistration.
-type secretEventHandlerRegistration struct {
-	cache.ResourceEventHandlerRegistration
+	// RestartSecretInformer replaces the informer serving the provided registration with a fresh one,
+	// and waits for it to sync. Handlers still registered with the informer don't need to be re-added.
+	RestartSecretInformer(context.Context, SecretEventHandlerRegistration) error
 
-	// objectKey represents the unique identifier for the secret associated with this event handler registration.
-	// It will be populated during AddEventHandler, and will be used during RemoveEventHandler, GetSecret.
-	objectKey ObjectKey
-}
+	// Status returns the health of the informer monitoring the secret with the given key,
+	// so that controllers can surface degraded secret watches.
+	Status(ObjectKey) (MonitorStatus, error)
 
-func (r *secretEventHandlerRegistration) GetKey() ObjectKey {
-	return r.objectKey
-}
+	// StatusAll returns the health of all secret informers, sorted by key.
+	StatusAll() []MonitorStatus
 
-func (r *secretEventHandlerRegistration) GetHandler() cache.ResourceEventHandlerRegistration {
-	return r.ResourceEventHandlerRegistration
-}
+	// DroppedUpdates returns the number of update notifications dropped because they did not change
+	// the content compared by the UpdateFilter of the monitor, counted once per handler.
+	// Always zero if the monitor has no UpdateFilter.
+	DroppedUpdates() int64
+
+	// AddSecretSelectorEventHandler adds a secret event handler to the monitor for all secrets in the given
+	// namespace matching the label selector. The handler will be notified with an add event when a secret
+	// enters the selection, and with a delete event when it leaves the selection. It returns without waiting
+	// for the informer to sync; once the Ready channel of the returned SecretSelectorEventHandlerRegistration
+	// is closed, Err reports whether the sync failed. The registration can be used to later remove the handler.
+	AddSecretSelectorEventHandler(ctx context.Context, namespace string, selector labels.Selector, handler cache.ResourceEventHandler) (SecretSelectorEventHandlerRegistration, error)
+
+	// RemoveSecretSelectorEventHandler removes a previously added selector event handler using the provided registration.
+	// If the handler is not found or if there is an issue removing it, an error is returned.
+	RemoveSecretSelectorEventHandler(SecretSelectorEventHandlerRegistration) error
 
-type monitoredItem struct {
-	itemMonitor *singleItemMonitor
-	numHandlers atomic.Int32
+	// GetSecrets retrieves the secrets currently matching the label selector of the provided
+	// SecretSelectorEventHandlerRegistration from the informer's cache, sorted by name.
+	GetSecrets(SecretSelectorEventHandlerRegistration) ([]*v1.Secret, error)
 }
 
-// secretMonitor is an implementation of the SecretMonitor
+// secretMonitor is an implementation of the SecretMonitor,
+// specializing objectMonitor for secrets.
 type secretMonitor struct {
+	*objectMonitor[*v1.Secret]
+
+	// selectors monitors the secrets matching label selectors
+	selectors *selectorMonitor[*v1.Secret]
+
+	// dataKeys are the data keys needed by the registrations of each secret
+	dataKeys *secretDataKeys
+	// refreshing counts the registrations of each secret waiting for the cached secret to be refreshed
+	// with the data keys they need. Guarded by the lock of the objectMonitor.
+	refreshing map[ObjectKey]int
+
+	// updates drops the no-op updates before they reach the handlers. Optional.
+	updates *updateFilter
+
 	kubeClient kubernetes.Interface
-	lock       sync.RWMutex
-	monitors   map[ObjectKey]*monitoredItem
 }
 
 func NewSecretMonitor(kubeClient kubernetes.Interface) SecretMonitor {
-	return &secretMonitor{
-		kubeClient: kubeClient,
-		monitors:   map[ObjectKey]*monitoredItem{},
-	}
+	return newSecretMonitor(kubeClient)
 }
 
-// AddSecretEventHandler adds a secret event handler to the monitor.
-func (s *secretMonitor) AddSecretEventHandler(ctx context.Context, namespace, secretName string, handler cache.ResourceEventHandler) (SecretEventHandlerRegistration, error) {
-	return s.addSecretEventHandler(ctx, namespace, secretName, handler, s.createSecretInformer(namespace, secretName))
+// NewAdaptiveSecretMonitor returns a SecretMonitor which watches each secret separately until more than
+// namespaceThreshold secrets are monitored in a namespace. From then on, the namespace is monitored by a
+// single namespace-wide informer, until the number of secrets drops to half of namespaceThreshold.
+// Registrations stay valid across the switch.
+func NewAdaptiveSecretMonitor(kubeClient kubernetes.Interface, namespaceThreshold int) SecretMonitor {
+	s := newSecretMonitor(kubeClient)
+	s.withNamespaceInformer(namespaceThreshold, s.createNamespaceSecretInformer)
+	return s
 }
 
-// createSecretInformer creates a SharedInformer for monitoring a specific secret.
-func (s *secretMonitor) createSecretInformer(namespace, name string) func() cache.SharedInformer {
-	return func() cache.SharedInformer {
-		return cache.NewSharedInformer(
-			cache.NewListWatchFromClient(
-				s.kubeClient.CoreV1().RESTClient(),
-				"secrets",
-				namespace,
-				fields.OneTermEqualSelector("metadata.name", name),
-			),
-			&corev1.Secret{},
-			0,
-		)
+// NewFilteringSecretMonitor returns a SecretMonitor which does not notify the handlers about updates
+// leaving the type, data, and the labels and annotations selected by filter of a secret unchanged.
+func NewFilteringSecretMonitor(kubeClient kubernetes.Interface, filter UpdateFilter) SecretMonitor {
+	return newSecretMonitor(kubeClient).withUpdateFilter(filter)
+}
+
+func newSecretMonitor(kubeClient kubernetes.Interface) *secretMonitor {
+	s := &secretMonitor{
+		kubeClient: kubeClient,
+		dataKeys:   newSecretDataKeys(),
+		refreshing: map[ObjectKey]int{},
 	}
+	s.objectMonitor = newObjectMonitor[*v1.Secret](corev1.Resource("secrets"), s.createSecretInformer).withTransform(s.dataKeys.transform)
+	s.selectors = newSelectorMonitor[*v1.Secret](corev1.Resource("secrets"), s.createSelectorSecretInformer)
+	return s
 }
 
-// addSecretEventHandler adds a secret event handler and starts the informer if not already running.
-func (s *secretMonitor) addSecretEventHandler(ctx context.Context, namespace, secretName string, handler cache.ResourceEventHandler, createInformerFn func() cache.SharedInformer) (SecretEventHandlerRegistration, error) {
-	s.lock.Lock()
-	defer s.lock.Unlock()
+// withUpdateFilter drops the updates of secrets which leave the content compared by filter unchanged.
+func (s *secretMonitor) withUpdateFilter(filter UpdateFilter) *secretMonitor {
+	s.updates = &updateFilter{UpdateFilter: filter}
+	return s
+}
 
-	if handler == nil {
-		return nil, fmt.Errorf("nil handler is provided")
+// filtered wraps handler with the update filter of the monitor, if any.
+func (s *secretMonitor) filtered(handler cache.ResourceEventHandler) cache.ResourceEventHandler {
+	if s.updates == nil || handler == nil {
+		return handler
 	}
+	return s.updates.wrap(handler)
+}
 
-	// secret identifier (namespace/secret)
-	key := NewObjectKey(namespace, secretName)
-
-	// start secret informer if monitor does not exists
-	m, exists := s.monitors[key]
-	if !exists {
-		m = &monitoredItem{}
-		sharedInformer := createInformerFn()
-		m.itemMonitor = newSingleItemMonitor(key, sharedInformer)
-		go m.itemMonitor.StartInformer(ctx)
-
-		// wait for first sync
-		if !cache.WaitForCacheSync(context.Background().Done(), m.itemMonitor.HasSynced) {
-			return nil, fmt.Errorf("failed waiting for cache sync")
-		}
+// DroppedUpdates returns the number of no-op updates dropped by the update filter.
+func (s *secretMonitor) DroppedUpdates() int64 {
+	if s.updates == nil {
+		return 0
+	}
+	return s.updates.dropped.Load()
+}
 
-		// add item key to monitors map // add watch to the list
-		s.monitors[key] = m
+// AddSecretEventHandler adds a secret event handler to the monitor.
+func (s *secretMonitor) AddSecretEventHandler(ctx context.Context, namespace, secretName string, handler cache.ResourceEventHandler) (SecretEventHandlerRegistration, error) {
+	return s.addSecretDataEventHandler(ctx, namespace, secretName, nil, handler, func() cache.SharedInformer {
+		return s.createInformer(namespace, secretName)
+	})
+}
 
-		klog.Info("secret informer started", " item key ", key)
+// AddSecretDataEventHandler adds a secret event handler which only needs the given data keys of the secret.
+func (s *secretMonitor) AddSecretDataEventHandler(ctx context.Context, namespace, secretName string, dataKeys []string, handler cache.ResourceEventHandler) (SecretEventHandlerRegistration, error) {
+	if dataKeys == nil {
+		dataKeys = []string{}
 	}
+	return s.addSecretDataEventHandler(ctx, namespace, secretName, dataKeys, handler, func() cache.SharedInformer {
+		return s.createInformer(namespace, secretName)
+	})
+}
 
-	// add the event handler
-	registration, err := m.itemMonitor.AddEventHandler(handler)
+// AddSecretEventHandlerAsync adds a secret event handler to the monitor without waiting for the informer to sync.
+func (s *secretMonitor) AddSecretEventHandlerAsync(ctx context.Context, namespace, secretName string, handler cache.ResourceEventHandler) (AsyncSecretEventHandlerRegistration, error) {
+	r, err := s.addSecretDataEventHandlerAsync(ctx, namespace, secretName, nil, handler, func() cache.SharedInformer {
+		return s.createInformer(namespace, secretName)
+	})
 	if err != nil {
 		return nil, err
 	}
-	m.numHandlers.Add(1)
-	klog.Info("secret handler added", " item key ", key)
 
-	return registration, nil
+	go func() {
+		<-r.Ready()
+		if r.Err() != nil {
+			// failed registrations are removed by the monitor
+			s.dataKeys.remove(r)
+		}
+	}()
+
+	return r, nil
 }
 
-// RemoveSecretEventHandler removes a secret event handler and stops the informer if no handlers are left.
-// If the handler is not found or if there is an issue removing it, an error is returned.
-func (s *secretMonitor) RemoveSecretEventHandler(handlerRegistration SecretEventHandlerRegistration) error {
+// addSecretDataEventHandlerAsync adds a secret event handler which needs dataKeys, or all data keys if nil,
+// without waiting for the informer to sync. The data keys are acquired and the handler is added under the
+// lock, so that whether the cached secret lacks any of them is decided consistently with the registrations
+// and namespace switches of other callers.
+func (s *secretMonitor) addSecretDataEventHandlerAsync(ctx context.Context, namespace, secretName string, dataKeys []string, handler cache.ResourceEventHandler, createInformerFn func() cache.SharedInformer) (*objectEventHandlerRegistration, error) {
+	key := NewObjectKey(namespace, secretName)
+
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	if handlerRegistration == nil {
-		return fmt.Errorf("secret handler is nil")
-	}
+	prepare := s.refresher(ctx, key, dataKeys)
+	s.dataKeys.acquire(key, dataKeys)
 
-	// Extract the key from the registration to identify the associated monitor.
-	// populated in AddEventHandler()
-	key := handlerRegistration.GetKey()
-
-	// check if secret informer already exists for the secret(key)
-	m, exists := s.monitors[key]
-	if !exists {
-		return fmt.Errorf("secret monitor already removed for item key %v", key)
+	r, err := s.addEventHandlerLocked(ctx, namespace, secretName, s.filtered(handler), createInformerFn, prepare)
+	if err != nil {
+		s.dataKeys.release(key, dataKeys)
+		if prepare != nil {
+			s.doneRefreshing(key)
+		}
+		return nil, err
 	}
+	s.dataKeys.bind(r, dataKeys)
 
-	if err := m.itemMonitor.RemoveEventHandler(handlerRegistration); err != nil {
-		return err
-	}
-	m.numHandlers.Add(-1)
-	klog.Info("secret handler removed", " item key", key)
+	return r, nil
+}
 
-	// stop informer if there is no handler
-	if m.numHandlers.Load() <= 0 {
-		if !m.itemMonitor.StopInformer() {
-			klog.Error("secret informer already stopped", " item key", key)
+// refresher returns the function refreshing the cached secret once the informer of a new registration needing
+// dataKeys has synced, since data keys stripped from the cached secret are not restored by later events. Nil if
+// the cached secret already has all of them, or is listed by a new informer after they are acquired.
+// Must be called with the lock held, before the data keys are acquired.
+func (s *secretMonitor) refresher(ctx context.Context, key ObjectKey, dataKeys []string) func(ObjectEventHandlerRegistration) error {
+	_, monitored := s.namespaces[key.Namespace]
+	_, switching := s.switching[key.Namespace]
+	if s.dataKeys.registered(key) {
+		// the keys of the other registrations may still be refreshing
+		if s.dataKeys.covers(key, dataKeys) && s.refreshing[key] == 0 {
+			return nil
 		}
-		// remove the key from map
-		delete(s.monitors, key)
-		klog.Info("secret informer stopped", " item key ", key)
+	} else if !monitored && !switching {
+		// a new per-secret informer is started, whose first list already keeps the data keys
+		return nil
 	}
 
-	return nil
+	s.refreshing[key]++
+	return func(r ObjectEventHandlerRegistration) error {
+		defer s.doneRefreshing(key)
+		return s.refresh(ctx, r)
+	}
 }
 
-// GetSecret retrieves the secret object from the informer's cache. Error if the secret is not found in the cache.
-func (s *secretMonitor) GetSecret(handlerRegistration SecretEventHandlerRegistration) (*v1.Secret, error) {
-	s.lock.RLock()
-	defer s.lock.RUnlock()
-
-	if handlerRegistration == nil {
-		return nil, fmt.Errorf("secret handler is nil")
+// doneRefreshing records that a registration of the secret no longer waits for it to be refreshed.
+// Must be called without the lock held.
+func (s *secretMonitor) doneRefreshing(key ObjectKey) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.refreshing[key]--; s.refreshing[key] <= 0 {
+		delete(s.refreshing, key)
 	}
-	key := handlerRegistration.GetKey()
-	secretName := key.Name
+}
 
-	// check if secret informer exists
-	m, exists := s.monitors[key]
-	if !exists {
-		return nil, fmt.Errorf("secret monitor doesn't exist for key %v", key)
-	}
+// refresh makes the cached secret of r include the data keys stripped before r was registered.
+// A per-secret informer is restarted, which re-lists only that secret. The secret of a namespace-wide
+// informer is read with a single Get instead, rather than restarting the informer of the whole namespace,
+// and replaces the cached secret once the informer has dispatched the same version.
+func (s *secretMonitor) refresh(ctx context.Context, r ObjectEventHandlerRegistration) error {
+	key := r.GetKey()
 
-	// wait for informer store sync, to load secrets
-	if !cache.WaitForCacheSync(context.Background().Done(), handlerRegistration.HasSynced) {
-		return nil, fmt.Errorf("failed waiting for cache sync")
+	s.lock.RLock()
+	n, exists := s.namespaces[key.Namespace]
+	s.lock.RUnlock()
+	registration, ok := r.(*objectEventHandlerRegistration)
+	if !exists || !ok || !n.hasName(key.Name) {
+		return s.Restart(ctx, r)
 	}
 
-	uncast, exists, err := m.itemMonitor.GetItem()
-	if !exists {
-		return nil, apierrors.NewNotFound(corev1.Resource("secrets"), secretName)
+	err := wait.PollUntilContextTimeout(ctx, syncPollPeriod, s.syncTimeout, true, func(ctx context.Context) (bool, error) {
+		secret, err := s.kubeClient.CoreV1().Secrets(key.Namespace).Get(ctx, key.Name, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			return n.refresh(registration, nil), nil
+		case apierrors.IsForbidden(err):
+			return false, err
+		case err != nil:
+			klog.Error(err)
+			return false, nil
+		}
+		obj, err := s.dataKeys.transform(secret)
+		if err != nil {
+			return false, err
+		}
+		return n.refresh(registration, obj), nil
+	})
+	if err != nil {
+		return fmt.Errorf("secret %v not refreshed with the data keys of the registration: %w", key, err)
 	}
+	return nil
+}
 
+// addSecretDataEventHandler adds a secret event handler which needs dataKeys, or all data keys if nil,
+// and waits for the informer to sync and the cached secret to have all the data keys.
+func (s *secretMonitor) addSecretDataEventHandler(ctx context.Context, namespace, secretName string, dataKeys []string, handler cache.ResourceEventHandler, createInformerFn func() cache.SharedInformer) (SecretEventHandlerRegistration, error) {
+	r, err := s.addSecretDataEventHandlerAsync(ctx, namespace, secretName, dataKeys, handler, createInformerFn)
 	if err != nil {
 		return nil, err
 	}
 
-	secret, ok := uncast.(*v1.Secret)
-	if !ok {
-		return nil, fmt.Errorf("unexpected type: %T", uncast)
+	<-r.Ready()
+	if err := r.Err(); err != nil {
+		// failed registrations are removed by the monitor
+		s.dataKeys.remove(r)
+		return nil, err
 	}
 
-	return secret, nil
+	return r, nil
+}
+
+// createSecretInformer creates a SharedInformer for monitoring a specific secret.
+func (s *secretMonitor) createSecretInformer(namespace, name string) cache.SharedInformer {
+	return s.newSecretInformer(namespace, func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+	})
+}
+
+// createNamespaceSecretInformer creates a SharedInformer for monitoring all secrets of a namespace.
+func (s *secretMonitor) createNamespaceSecretInformer(namespace string) cache.SharedInformer {
+	return s.newSecretInformer(namespace, func(options *metav1.ListOptions) {})
+}
+
+// createSelectorSecretInformer creates a SharedInformer for monitoring the secrets matching a label selector.
+func (s *secretMonitor) createSelectorSecretInformer(namespace string, selector labels.Selector) cache.SharedInformer {
+	return s.newSecretInformer(namespace, func(options *metav1.ListOptions) {
+		options.LabelSelector = selector.String()
+	})
+}
+
+// newSecretInformer creates a SharedInformer for the secrets of a namespace, restricted by tweakListOptions.
+// It lists and watches through the typed client rather than its RESTClient, so that it also works with
+// fake clientsets.
+func (s *secretMonitor) newSecretInformer(namespace string, tweakListOptions func(*metav1.ListOptions)) cache.SharedInformer {
+	return newObservedInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				tweakListOptions(&options)
+				return s.kubeClient.CoreV1().Secrets(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				tweakListOptions(&options)
+				return s.kubeClient.CoreV1().Secrets(namespace).Watch(context.TODO(), options)
+			},
+		},
+		&corev1.Secret{},
+	)
+}
+
+// addSecretEventHandler adds a secret event handler and starts the informer if not already running.
+func (s *secretMonitor) addSecretEventHandler(ctx context.Context, namespace, secretName string, handler cache.ResourceEventHandler, createInformerFn func() cache.SharedInformer) (SecretEventHandlerRegistration, error) {
+	return s.addSecretDataEventHandler(ctx, namespace, secretName, nil, handler, createInformerFn)
+}
+
+// RemoveSecretEventHandler removes a secret event handler and stops the informer if no handlers are left.
+// If the handler is not found or if there is an issue removing it, an error is returned.
+func (s *secretMonitor) RemoveSecretEventHandler(handlerRegistration SecretEventHandlerRegistration) error {
+	if err := s.RemoveEventHandler(handlerRegistration); err != nil {
+		return err
+	}
+	s.dataKeys.remove(handlerRegistration)
+	return nil
+}
+
+// GetSecret retrieves the secret object from the informer's cache. Error if the secret is not found in the cache.
+func (s *secretMonitor) GetSecret(handlerRegistration SecretEventHandlerRegistration) (*v1.Secret, error) {
+	return s.Get(handlerRegistration)
+}
+
+// RestartSecretInformer restarts the informer serving the registration.
+func (s *secretMonitor) RestartSecretInformer(ctx context.Context, handlerRegistration SecretEventHandlerRegistration) error {
+	return s.Restart(ctx, handlerRegistration)
+}
+
+// AddSecretSelectorEventHandler adds a secret event handler for all secrets matching the label selector.
+func (s *secretMonitor) AddSecretSelectorEventHandler(ctx context.Context, namespace string, selector labels.Selector, handler cache.ResourceEventHandler) (SecretSelectorEventHandlerRegistration, error) {
+	return s.selectors.AddEventHandler(ctx, namespace, selector, s.filtered(handler))
+}
+
+// RemoveSecretSelectorEventHandler removes a selector event handler and stops the informer if no handlers are left.
+func (s *secretMonitor) RemoveSecretSelectorEventHandler(handlerRegistration SecretSelectorEventHandlerRegistration) error {
+	return s.selectors.RemoveEventHandler(handlerRegistration)
+}
+
+// GetSecrets retrieves the secrets matching the label selector from the informer's cache.
+func (s *secretMonitor) GetSecrets(handlerRegistration SecretSelectorEventHandlerRegistration) ([]*v1.Secret, error) {
+	return s.selectors.List(handlerRegistration)
 }
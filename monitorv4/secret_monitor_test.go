@@ -2,11 +2,23 @@ package secret
 
 import (
 	"context"
+	"fmt"
 	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -47,10 +59,7 @@ func TestAddSecretEventHandler(t *testing.T) {
 			fakeInformer := func() cache.SharedInformer {
 				return fakeSecretInformer(context.TODO(), fakeKubeClient, namespace, secretName)
 			}
-			sm := secretMonitor{
-				kubeClient: fakeKubeClient,
-				monitors:   map[ObjectKey]*monitoredItem{},
-			}
+			sm := newSecretMonitor(fakeKubeClient)
 
 			gotErr := 0
 			for i := 0; i < s.numInvocation; i++ {
@@ -129,10 +138,7 @@ func TestRemoveSecretEventHandler(t *testing.T) {
 				return fakeSecretInformer(context.TODO(), fakeKubeClient, namespace, secretName)
 			}
 			key := NewObjectKey(namespace, secretName)
-			sm := secretMonitor{
-				kubeClient: fakeKubeClient,
-				monitors:   map[ObjectKey]*monitoredItem{},
-			}
+			sm := newSecretMonitor(fakeKubeClient)
 
 			handlers := []SecretEventHandlerRegistration{}
 			for i := 0; i < s.numAddition; i++ {
@@ -234,10 +240,7 @@ func TestGetSecret(t *testing.T) {
 				return fakeSecretInformer(context.TODO(), fakeKubeClient, namespace, secretName)
 			}
 			key := NewObjectKey(namespace, secretName)
-			sm := secretMonitor{
-				kubeClient: fakeKubeClient,
-				monitors:   map[ObjectKey]*monitoredItem{},
-			}
+			sm := newSecretMonitor(fakeKubeClient)
 			h, err := sm.addSecretEventHandler(context.TODO(), key.Namespace, key.Name, cache.ResourceEventHandlerFuncs{}, fakeInformer)
 			if err != nil {
 				t.Error(err)
@@ -263,3 +266,788 @@ func TestGetSecret(t *testing.T) {
 		})
 	}
 }
+
+func TestNamespaceThreshold(t *testing.T) {
+	var (
+		namespace = "ns"
+		names     = []string{"secret-0", "secret-1", "secret-2"}
+	)
+
+	objects := []runtime.Object{}
+	for _, name := range names {
+		objects = append(objects, fakeSecret(namespace, name))
+	}
+	fakeKubeClient := fake.NewSimpleClientset(objects...)
+	sm := fakeSecretMonitor(fakeKubeClient)
+	sm.withNamespaceInformer(2, fakeNamespaceSecretInformer(context.TODO(), fakeKubeClient))
+
+	updates := make(chan string, 10)
+	adds := make(chan string, 10)
+	handlers := []SecretEventHandlerRegistration{}
+	for i, name := range names {
+		name := name
+		h, err := sm.AddSecretEventHandler(context.TODO(), namespace, name, cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				adds <- name + "/" + obj.(*corev1.Secret).Name
+			},
+			UpdateFunc: func(old, new interface{}) {
+				updates <- name + "/" + new.(*corev1.Secret).Name
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		handlers = append(handlers, h)
+
+		// the third secret crosses the threshold
+		expectNamespaceInformer := i >= 2
+		if _, exist := sm.namespaces[namespace]; exist != expectNamespaceInformer {
+			t.Errorf("expected namespace informer %t, got %t", expectNamespaceInformer, exist)
+		}
+	}
+	if len(sm.monitors) != 0 {
+		t.Errorf("expected per-secret informers to be stopped, got %d", len(sm.monitors))
+	}
+
+	// each handler is notified once about its own secret, also by the informer it was moved to
+	expectAdds := func(expected sets.String) {
+		t.Helper()
+		for _, h := range handlers {
+			if !cache.WaitForCacheSync(context.TODO().Done(), h.HasSynced) {
+				t.Fatal("handler failed to sync")
+			}
+		}
+		got := sets.NewString()
+		for len(adds) > 0 {
+			add := <-adds
+			if got.Has(add) {
+				t.Errorf("duplicate add %s", add)
+			}
+			got.Insert(add)
+		}
+		if !got.Equal(expected) {
+			t.Errorf("expected adds %v, got %v", expected.List(), got.List())
+		}
+	}
+	expectAdds(sets.NewString("secret-0/secret-0", "secret-1/secret-1", "secret-2/secret-2"))
+
+	// registrations created before the switch keep working
+	for i, h := range handlers {
+		secret, err := sm.GetSecret(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret.Name != names[i] {
+			t.Errorf("expected %s got %s", names[i], secret.Name)
+		}
+	}
+
+	// events are only delivered to the handlers of the updated secret
+	updated := fakeSecret(namespace, names[0])
+	updated.Data["test"] = []byte{5}
+	if _, err := fakeKubeClient.CoreV1().Secrets(namespace).Update(context.TODO(), updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-updates:
+		if expected := names[0] + "/" + names[0]; got != expected {
+			t.Errorf("expected update %s got %s", expected, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for update")
+	}
+
+	// dropping to half of the threshold switches back to per-secret informers in the background
+	for _, h := range handlers[1:] {
+		if err := sm.RemoveSecretEventHandler(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	key := NewObjectKey(namespace, names[0])
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		sm.lock.RLock()
+		defer sm.lock.RUnlock()
+		_, exist := sm.namespaces[namespace]
+		return !exist, nil
+	}); err != nil {
+		t.Fatal("namespace informer should be stopped")
+	}
+	sm.lock.RLock()
+	m, exist := sm.monitors[key]
+	sm.lock.RUnlock()
+	if !exist || m.numHandlers.Load() != 1 {
+		t.Fatal("per-secret informer should be started for", key)
+	}
+	if _, err := sm.GetSecret(handlers[0]); err != nil {
+		t.Error(err)
+	}
+	handlers = handlers[:1]
+	expectAdds(sets.NewString())
+
+	if err := sm.RemoveSecretEventHandler(handlers[0]); err != nil {
+		t.Error(err)
+	}
+	if len(sm.monitors) != 0 {
+		t.Errorf("expected no informers, got %d", len(sm.monitors))
+	}
+}
+
+func TestNamespaceDispatcher(t *testing.T) {
+	var (
+		namespace = "ns"
+		threshold = 2
+		names     = []string{}
+	)
+
+	objects := []runtime.Object{}
+	for i := 0; i < 10; i++ {
+		names = append(names, fmt.Sprintf("secret-%d", i))
+		objects = append(objects, fakeSecret(namespace, names[i]))
+	}
+	fakeKubeClient := fake.NewSimpleClientset(objects...)
+	sm := fakeSecretMonitor(fakeKubeClient)
+	sm.withNamespaceInformer(threshold, fakeNamespaceSecretInformer(context.TODO(), fakeKubeClient))
+
+	// the registration crossing the threshold is made with a context which is canceled right after
+	ctx, cancel := context.WithCancel(context.Background())
+	var adds, updates [10]atomic.Int32
+	handlers := []SecretEventHandlerRegistration{}
+	for i, name := range names {
+		i := i
+		registrationCtx := context.TODO()
+		if i == threshold {
+			registrationCtx = ctx
+		}
+		h, err := sm.AddSecretEventHandler(registrationCtx, namespace, name, cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) { adds[i].Add(1) },
+			UpdateFunc: func(old, new interface{}) {
+				if reflect.DeepEqual(new.(*corev1.Secret).Data["test"], []byte{5}) {
+					updates[i].Add(1)
+				}
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		handlers = append(handlers, h)
+	}
+	cancel()
+
+	sm.lock.RLock()
+	n := sm.namespaces[namespace]
+	sm.lock.RUnlock()
+	if n == nil {
+		t.Fatal("expected a namespace informer")
+	}
+	n.itemMonitor.lock.Lock()
+	numInformerHandlers := len(n.itemMonitor.registrations)
+	n.itemMonitor.lock.Unlock()
+	if numInformerHandlers != 1 {
+		t.Errorf("expected a single handler on the namespace informer, got %d", numInformerHandlers)
+	}
+	for i, h := range handlers {
+		if !cache.WaitForCacheSync(context.TODO().Done(), h.HasSynced) {
+			t.Fatal("handler failed to sync")
+		}
+		if got := adds[i].Load(); got != 1 {
+			t.Errorf("expected one add for %s, got %d", names[i], got)
+		}
+	}
+
+	// the namespace informer keeps running after the context of the registration is canceled,
+	// and notifies only the handler of the updated secret
+	updated := fakeSecret(namespace, names[5])
+	updated.Data["test"] = []byte{5}
+	if _, err := fakeKubeClient.CoreV1().Secrets(namespace).Update(context.TODO(), updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return updates[5].Load() == 1, nil
+	}); err != nil {
+		t.Fatal("timed out waiting for update")
+	}
+	for i := range names {
+		if got := updates[i].Load(); i != 5 && got != 0 {
+			t.Errorf("expected no update for %s, got %d", names[i], got)
+		}
+	}
+
+	// the namespace informer is stopped with the removal of the last handler
+	for _, h := range handlers {
+		if err := sm.RemoveSecretEventHandler(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-n.ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("expected the context of the namespace informer to be canceled")
+	}
+}
+
+// blockingSecretInformer returns an informer which lists/watches the secrets of the namespace,
+// but whose lists block while blocked is set, until release is closed.
+func blockingSecretInformer(fakeKubeClient *fake.Clientset, namespace string, blocked *atomic.Bool, release chan struct{}) cache.SharedInformer {
+	return cache.NewSharedInformer(&cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			if blocked.Load() {
+				<-release
+			}
+			return fakeKubeClient.CoreV1().Secrets(namespace).List(context.TODO(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return fakeKubeClient.CoreV1().Secrets(namespace).Watch(context.TODO(), options)
+		},
+	},
+		&corev1.Secret{},
+		0,
+	)
+}
+
+func TestNamespaceSwitchWithoutLock(t *testing.T) {
+	var (
+		namespace = "ns"
+		names     = []string{"secret-0", "secret-1", "secret-2"}
+	)
+
+	objects := []runtime.Object{fakeSecret("other", "secret")}
+	for _, name := range names {
+		objects = append(objects, fakeSecret(namespace, name))
+	}
+	fakeKubeClient := fake.NewSimpleClientset(objects...)
+
+	// the lists of the informers of the namespace block once enabled, until released
+	var blockNamespace, blockItems, never atomic.Bool
+	releaseNamespace, releaseItems := make(chan struct{}), make(chan struct{})
+	sm := newSecretMonitor(fakeKubeClient)
+	sm.createInformer = func(ns, name string) cache.SharedInformer {
+		if ns != namespace {
+			return blockingSecretInformer(fakeKubeClient, ns, &never, nil)
+		}
+		return blockingSecretInformer(fakeKubeClient, ns, &blockItems, releaseItems)
+	}
+	sm.withNamespaceInformer(2, func(ns string) cache.SharedInformer {
+		return blockingSecretInformer(fakeKubeClient, ns, &blockNamespace, releaseNamespace)
+	})
+
+	handlers := []SecretEventHandlerRegistration{}
+	for _, name := range names[:2] {
+		h, err := sm.AddSecretEventHandler(context.TODO(), namespace, name, cache.ResourceEventHandlerFuncs{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		handlers = append(handlers, h)
+	}
+
+	// the monitor stays usable while a switch waits for its informers to sync
+	expectUsable := func() {
+		t.Helper()
+		done := make(chan error)
+		go func() {
+			if _, err := sm.GetSecret(handlers[0]); err != nil {
+				done <- err
+				return
+			}
+			h, err := sm.AddSecretEventHandler(context.TODO(), "other", "secret", cache.ResourceEventHandlerFuncs{})
+			if err != nil {
+				done <- err
+				return
+			}
+			done <- sm.RemoveSecretEventHandler(h)
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Error(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("monitor blocked while switching")
+		}
+	}
+
+	// the third secret crosses the threshold, and is ready once the namespace-wide informer is synced
+	blockNamespace.Store(true)
+	var third AsyncSecretEventHandlerRegistration
+	added := make(chan error)
+	go func() {
+		var err error
+		third, err = sm.AddSecretEventHandlerAsync(context.TODO(), namespace, names[2], cache.ResourceEventHandlerFuncs{})
+		added <- err
+	}()
+	select {
+	case err := <-added:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("async registration blocked while switching")
+	}
+	sm.lock.RLock()
+	_, switching := sm.switching[namespace]
+	sm.lock.RUnlock()
+	if !switching {
+		t.Fatal("expected the namespace to be switching")
+	}
+	if third.HasSynced() {
+		t.Error("expected the registration not to be ready before the namespace informer syncs")
+	}
+	expectUsable()
+	close(releaseNamespace)
+	select {
+	case <-third.Ready():
+		if err := third.Err(); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the namespace informer")
+	}
+	handlers = append(handlers, third)
+
+	// dropping to half of the threshold switches back, while the per-secret informers are synced
+	blockItems.Store(true)
+	for _, h := range handlers[1:] {
+		if err := sm.RemoveSecretEventHandler(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectUsable()
+	close(releaseItems)
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		sm.lock.RLock()
+		defer sm.lock.RUnlock()
+		_, exist := sm.monitors[NewObjectKey(namespace, names[0])]
+		return exist && len(sm.namespaces) == 0, nil
+	}); err != nil {
+		t.Fatal("expected a per-secret informer for", names[0])
+	}
+	if _, err := sm.GetSecret(handlers[0]); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAddSecretEventHandlerAsync(t *testing.T) {
+	var (
+		secretName = "secret"
+		slowKey    = NewObjectKey("slow", secretName)
+		fastKey    = NewObjectKey("fast", secretName)
+		deniedKey  = NewObjectKey("denied", secretName)
+	)
+
+	fakeKubeClient := fake.NewSimpleClientset(fakeSecret(slowKey.Namespace, secretName), fakeSecret(fastKey.Namespace, secretName))
+	fakeKubeClient.PrependReactor("list", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() == deniedKey.Namespace {
+			return true, nil, apierrors.NewForbidden(corev1.Resource("secrets"), "", fmt.Errorf("denied"))
+		}
+		return false, nil, nil
+	})
+	// the list of the slow secret blocks until released
+	listing, release := make(chan struct{}), make(chan struct{})
+	var listingOnce sync.Once
+	sm := newSecretMonitor(fakeKubeClient)
+	sm.createInformer = func(namespace, name string) cache.SharedInformer {
+		return cache.NewSharedInformer(&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if namespace == slowKey.Namespace {
+					listingOnce.Do(func() { close(listing) })
+					<-release
+				}
+				return fakeKubeClient.CoreV1().Secrets(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return fakeKubeClient.CoreV1().Secrets(namespace).Watch(context.TODO(), options)
+			},
+		},
+			&corev1.Secret{},
+			0,
+		)
+	}
+
+	isReady := func(h AsyncSecretEventHandlerRegistration) bool {
+		select {
+		case <-h.Ready():
+			return true
+		case <-time.After(2 * time.Second):
+			return false
+		}
+	}
+
+	slow, err := sm.AddSecretEventHandlerAsync(context.TODO(), slowKey.Namespace, secretName, cache.ResourceEventHandlerFuncs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// reading the slow secret waits for its informer, which is blocked in its list
+	got := make(chan error, 1)
+	reading := make(chan struct{})
+	go func() {
+		close(reading)
+		_, err := sm.GetSecret(slow)
+		got <- err
+	}()
+	<-reading
+	<-listing
+
+	// a slow informer, also when read meanwhile, does not block the registration of other secrets
+	var fast AsyncSecretEventHandlerRegistration
+	added := make(chan error)
+	go func() {
+		var err error
+		fast, err = sm.AddSecretEventHandlerAsync(context.TODO(), fastKey.Namespace, secretName, cache.ResourceEventHandlerFuncs{})
+		added <- err
+	}()
+	select {
+	case err := <-added:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("registration blocked by the slow informer")
+	}
+	if !isReady(fast) {
+		t.Fatal("fast registration should be ready")
+	}
+	if err := fast.Err(); err != nil {
+		t.Error(err)
+	}
+	select {
+	case <-slow.Ready():
+		t.Fatal("slow registration should not be ready before its list returns")
+	case err := <-got:
+		t.Fatalf("slow secret should not be read before its list returns, got %v", err)
+	default:
+	}
+
+	close(release)
+	if !isReady(slow) {
+		t.Fatal("slow registration should be ready")
+	}
+	if err := slow.Err(); err != nil {
+		t.Error(err)
+	}
+	if err := <-got; err != nil {
+		t.Error(err)
+	}
+
+	// a failed sync is reported through Err, and the informer is removed
+	denied, err := sm.AddSecretEventHandlerAsync(context.TODO(), deniedKey.Namespace, secretName, cache.ResourceEventHandlerFuncs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isReady(denied) {
+		t.Fatal("denied registration should be ready")
+	}
+	if !IsSyncForbidden(denied.Err()) {
+		t.Errorf("expected forbidden sync error, got %v", denied.Err())
+	}
+	sm.lock.RLock()
+	defer sm.lock.RUnlock()
+	if _, exist := sm.monitors[deniedKey]; exist {
+		t.Error("monitor key should be removed from map", deniedKey)
+	}
+}
+
+func TestAddSecretDataEventHandler(t *testing.T) {
+	var (
+		namespace  = "ns"
+		secretName = "secret"
+		routeKeys  = []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey}
+	)
+
+	scenarios := []struct {
+		name         string
+		registerKeys [][]string
+		// async registers the handlers needing all keys with AddSecretEventHandlerAsync
+		async          bool
+		expectDataKeys []string
+	}{
+		{
+			name:           "only the needed keys are cached",
+			registerKeys:   [][]string{routeKeys},
+			expectDataKeys: routeKeys,
+		},
+		{
+			name:           "union of needed keys is cached",
+			registerKeys:   [][]string{{corev1.TLSCertKey}, {"ca.crt"}},
+			expectDataKeys: []string{"ca.crt", corev1.TLSCertKey},
+		},
+		{
+			name:           "later registration needing all keys re-lists the secret",
+			registerKeys:   [][]string{routeKeys, nil},
+			expectDataKeys: []string{"ca.crt", "extra", corev1.TLSCertKey, corev1.TLSPrivateKeyKey},
+		},
+		{
+			name:           "later async registration needing all keys is ready once the secret is re-listed",
+			registerKeys:   [][]string{routeKeys, nil},
+			async:          true,
+			expectDataKeys: []string{"ca.crt", "extra", corev1.TLSCertKey, corev1.TLSPrivateKeyKey},
+		},
+		{
+			name:           "earlier registration needing all keys is not affected",
+			registerKeys:   [][]string{nil, routeKeys},
+			expectDataKeys: []string{"ca.crt", "extra", corev1.TLSCertKey, corev1.TLSPrivateKeyKey},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			secret := fakeSecret(namespace, secretName)
+			secret.Data = map[string][]byte{
+				corev1.TLSCertKey:       []byte("cert"),
+				corev1.TLSPrivateKeyKey: []byte("key"),
+				"ca.crt":                []byte("ca"),
+				"extra":                 []byte("extra"),
+			}
+			fakeKubeClient := fake.NewSimpleClientset(secret)
+			sm := fakeSecretMonitor(fakeKubeClient)
+
+			var registrations []SecretEventHandlerRegistration
+			for _, dataKeys := range s.registerKeys {
+				var h SecretEventHandlerRegistration
+				var err error
+				switch {
+				case dataKeys == nil && s.async:
+					var async AsyncSecretEventHandlerRegistration
+					async, err = sm.AddSecretEventHandlerAsync(context.TODO(), namespace, secretName, cache.ResourceEventHandlerFuncs{})
+					if err == nil {
+						<-async.Ready()
+						h, err = async, async.Err()
+					}
+				case dataKeys == nil:
+					h, err = sm.AddSecretEventHandler(context.TODO(), namespace, secretName, cache.ResourceEventHandlerFuncs{})
+				default:
+					h, err = sm.AddSecretDataEventHandler(context.TODO(), namespace, secretName, dataKeys, cache.ResourceEventHandlerFuncs{})
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				registrations = append(registrations, h)
+			}
+
+			for _, h := range registrations {
+				got, err := sm.GetSecret(h)
+				if err != nil {
+					t.Fatal(err)
+				}
+				gotKeys := []string{}
+				for dataKey := range got.Data {
+					gotKeys = append(gotKeys, dataKey)
+				}
+				sort.Strings(gotKeys)
+				if !reflect.DeepEqual(s.expectDataKeys, gotKeys) {
+					t.Errorf("expected data keys %v, got %v", s.expectDataKeys, gotKeys)
+				}
+			}
+
+			for _, h := range registrations {
+				if err := sm.RemoveSecretEventHandler(h); err != nil {
+					t.Error(err)
+				}
+			}
+			if len(sm.dataKeys.needed) != 0 || len(sm.dataKeys.registrations) != 0 {
+				t.Errorf("expected data keys to be released, got %v", sm.dataKeys.needed)
+			}
+		})
+	}
+}
+
+func TestAddSecretDataEventHandlerConcurrently(t *testing.T) {
+	var (
+		namespace  = "ns"
+		secretName = "secret"
+		allKeys    = []string{"a", "b", "c", "d"}
+	)
+
+	scenarios := []struct {
+		name string
+		// threshold switches the namespace to a namespace-wide informer before the registrations, if not zero
+		threshold int
+	}{
+		{
+			name: "per-secret informer",
+		},
+		{
+			name:      "namespace informer",
+			threshold: 1,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			secret := fakeSecret(namespace, secretName)
+			secret.Data = map[string][]byte{}
+			for _, dataKey := range allKeys {
+				secret.Data[dataKey] = []byte(dataKey)
+			}
+			fakeKubeClient := fake.NewSimpleClientset(secret, fakeSecret(namespace, "other-0"), fakeSecret(namespace, "other-1"))
+			sm := fakeSecretMonitor(fakeKubeClient)
+			if s.threshold > 0 {
+				sm.withNamespaceInformer(s.threshold, fakeNamespaceSecretInformer(context.TODO(), fakeKubeClient))
+				for _, name := range []string{"other-0", "other-1"} {
+					if _, err := sm.AddSecretEventHandler(context.TODO(), namespace, name, cache.ResourceEventHandlerFuncs{}); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if !sm.monitorsNamespace(namespace) {
+					t.Fatal("expected a namespace informer")
+				}
+			}
+
+			// each data key is needed by registrations made concurrently, along with registrations needing all keys
+			needed := [][]string{nil}
+			for _, dataKey := range allKeys {
+				needed = append(needed, []string{dataKey})
+			}
+			var wg sync.WaitGroup
+			registrations := make([]SecretEventHandlerRegistration, 4*len(needed))
+			errs := make(chan error, len(registrations))
+			for i := range registrations {
+				i := i
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					dataKeys := needed[i%len(needed)]
+					var err error
+					if dataKeys == nil {
+						registrations[i], err = sm.AddSecretEventHandler(context.TODO(), namespace, secretName, cache.ResourceEventHandlerFuncs{})
+					} else {
+						registrations[i], err = sm.AddSecretDataEventHandler(context.TODO(), namespace, secretName, dataKeys, cache.ResourceEventHandlerFuncs{})
+					}
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// every registration finds the data keys it needs once it is added
+			for i, h := range registrations {
+				got, err := sm.GetSecret(h)
+				if err != nil {
+					t.Fatal(err)
+				}
+				dataKeys := needed[i%len(needed)]
+				if dataKeys == nil {
+					dataKeys = allKeys
+				}
+				for _, dataKey := range dataKeys {
+					if _, exists := got.Data[dataKey]; !exists {
+						t.Errorf("expected data key %s in the secret of registration %d, got %v", dataKey, i, got.Data)
+					}
+				}
+			}
+			sm.lock.RLock()
+			defer sm.lock.RUnlock()
+			if len(sm.refreshing) != 0 {
+				t.Errorf("expected no refresh in progress, got %v", sm.refreshing)
+			}
+		})
+	}
+}
+
+func TestNamespaceInformerNotRestartedForData(t *testing.T) {
+	var (
+		namespace  = "ns"
+		threshold  = 10
+		numSecrets = 100
+	)
+
+	objects := []runtime.Object{}
+	for i := 0; i < numSecrets; i++ {
+		objects = append(objects, fakeSecret(namespace, fmt.Sprintf("secret-%d", i)))
+	}
+	fakeKubeClient := fake.NewSimpleClientset(objects...)
+	sm := fakeSecretMonitor(fakeKubeClient)
+	var numNamespaceInformers atomic.Int32
+	sm.withNamespaceInformer(threshold, func(namespace string) cache.SharedInformer {
+		numNamespaceInformers.Add(1)
+		return fakeNamespaceSecretInformer(context.TODO(), fakeKubeClient)(namespace)
+	})
+
+	// the secrets registered after the switch were cached without data, and are read again one by one
+	var numAdds atomic.Int32
+	handlers := []SecretEventHandlerRegistration{}
+	for i := 0; i < numSecrets; i++ {
+		h, err := sm.AddSecretEventHandler(context.TODO(), namespace, fmt.Sprintf("secret-%d", i), cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) { numAdds.Add(1) },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		handlers = append(handlers, h)
+	}
+	if n := numNamespaceInformers.Load(); n != 1 {
+		t.Errorf("expected a single namespace informer, got %d", n)
+	}
+	if n := numAdds.Load(); n != int32(numSecrets) {
+		t.Errorf("expected %d adds, got %d", numSecrets, n)
+	}
+	for _, h := range handlers {
+		secret, err := sm.GetSecret(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(secret.Data) == 0 {
+			t.Errorf("expected the data of %s to be cached", secret.Name)
+		}
+	}
+}
+
+func TestSecretDataKeysTransform(t *testing.T) {
+	var (
+		namespace = "ns"
+		routeKeys = []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey}
+	)
+
+	d := newSecretDataKeys()
+	d.acquire(NewObjectKey(namespace, "route"), routeKeys)
+	d.acquire(NewObjectKey(namespace, "full"), nil)
+
+	scenarios := []struct {
+		name           string
+		secretName     string
+		expectDataKeys []string
+	}{
+		{
+			name:           "only the needed keys are kept",
+			secretName:     "route",
+			expectDataKeys: routeKeys,
+		},
+		{
+			name:           "all keys are kept if needed",
+			secretName:     "full",
+			expectDataKeys: []string{"ca.crt", corev1.TLSCertKey, corev1.TLSPrivateKeyKey},
+		},
+		{
+			name:           "secret without registrations is stripped of all data",
+			secretName:     "other",
+			expectDataKeys: []string{},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			secret := fakeSecret(namespace, s.secretName)
+			secret.Data = map[string][]byte{
+				corev1.TLSCertKey:       []byte("cert"),
+				corev1.TLSPrivateKeyKey: []byte("key"),
+				"ca.crt":                []byte("ca"),
+			}
+			obj, err := d.transform(secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotKeys := []string{}
+			for dataKey := range obj.(*corev1.Secret).Data {
+				gotKeys = append(gotKeys, dataKey)
+			}
+			sort.Strings(gotKeys)
+			if !reflect.DeepEqual(s.expectDataKeys, gotKeys) {
+				t.Errorf("expected data keys %v, got %v", s.expectDataKeys, gotKeys)
+			}
+		})
+	}
+}
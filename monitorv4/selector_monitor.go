@@ -0,0 +1,282 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// SelectorKey represents the unique identifier for a set of resources matching a label selector.
+type SelectorKey struct {
+	// Namespace is the namespace in which the resources are located.
+	Namespace string
+	// Selector is the string representation of the label selector matching the resources.
+	Selector string
+}
+
+// NewSelectorKey creates a new SelectorKey for the given namespace and label selector.
+func NewSelectorKey(namespace string, selector labels.Selector) SelectorKey {
+	return SelectorKey{
+		Namespace: namespace,
+		Selector:  selector.String(),
+	}
+}
+
+// SelectorEventHandlerRegistration is for registering and unregistering event handlers for label selector monitoring.
+type SelectorEventHandlerRegistration interface {
+	cache.ResourceEventHandlerRegistration
+
+	GetKey() SelectorKey
+	GetHandler() cache.ResourceEventHandlerRegistration
+
+	// Ready returns a channel which is closed once the informer has synced, or failed to sync.
+	Ready() <-chan struct{}
+	// Err returns the error which occurred while syncing the informer, if any.
+	// It must only be called after the Ready channel is closed.
+	Err() error
+}
+
+// selectorEventHandlerRegistration is an implementation of the SelectorEventHandlerRegistration.
+type selectorEventHandlerRegistration struct {
+	// selectorKey represents the set of objects associated with this event handler registration.
+	selectorKey SelectorKey
+	// registration is the registration on the singleItemMonitor watching the selected objects.
+	registration *objectEventHandlerRegistration
+}
+
+func (r *selectorEventHandlerRegistration) GetKey() SelectorKey {
+	return r.selectorKey
+}
+
+func (r *selectorEventHandlerRegistration) GetHandler() cache.ResourceEventHandlerRegistration {
+	return r.registration.GetHandler()
+}
+
+func (r *selectorEventHandlerRegistration) HasSynced() bool {
+	return r.registration.HasSynced()
+}
+
+func (r *selectorEventHandlerRegistration) Ready() <-chan struct{} {
+	return r.registration.Ready()
+}
+
+func (r *selectorEventHandlerRegistration) Err() error {
+	return r.registration.Err()
+}
+
+// selectorMonitor monitors all objects of type T in a namespace which match a label selector.
+// Informers are shared between handlers registered with the same namespace and selector.
+type selectorMonitor[T runtime.Object] struct {
+	// resource is used while reporting errors
+	resource schema.GroupResource
+	// createInformer creates a SharedInformer for monitoring the objects matching a label selector
+	createInformer func(namespace string, selector labels.Selector) cache.SharedInformer
+	// syncTimeout bounds the time spent waiting for an informer cache to sync
+	syncTimeout time.Duration
+
+	lock     sync.RWMutex
+	monitors map[SelectorKey]*monitoredItem
+}
+
+func newSelectorMonitor[T runtime.Object](resource schema.GroupResource, createInformer func(namespace string, selector labels.Selector) cache.SharedInformer) *selectorMonitor[T] {
+	return &selectorMonitor[T]{
+		resource:       resource,
+		createInformer: createInformer,
+		monitors:       map[SelectorKey]*monitoredItem{},
+		syncTimeout:    DefaultSyncTimeout,
+	}
+}
+
+// matches returns true if obj carries labels matching the selector.
+func matches(selector labels.Selector, obj interface{}) bool {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(accessor.GetLabels()))
+}
+
+// AddEventHandler adds an event handler for the objects matching the selector in the given namespace,
+// and starts the informer if not already running. The handler is notified with an add event when an
+// object starts matching the selector, and with a delete event when it stops matching.
+// It returns without waiting for the informer to sync, which is awaited in the background without holding
+// the lock. Once the Ready channel of the returned registration is closed, Err reports whether the sync failed.
+// A registration which failed to sync does not need to be removed.
+func (s *selectorMonitor[T]) AddEventHandler(ctx context.Context, namespace string, selector labels.Selector, handler cache.ResourceEventHandler) (SelectorEventHandlerRegistration, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if handler == nil {
+		return nil, fmt.Errorf("nil handler is provided")
+	}
+	if selector == nil {
+		return nil, fmt.Errorf("nil selector is provided")
+	}
+
+	key := NewSelectorKey(namespace, selector)
+
+	// start informer if monitor does not exists
+	m, exists := s.monitors[key]
+	if !exists {
+		m = newMonitoredItem(NewObjectKey(namespace, ""), func() cache.SharedInformer {
+			return s.createInformer(namespace, selector)
+		})
+		m.itemMonitor.start(ctx)
+		s.monitors[key] = m
+		go s.waitForMonitoredItem(ctx, key, m)
+	}
+
+	// the informer may still deliver objects which stopped matching the selector,
+	// the filter turns their updates into delete events
+	r := newObjectEventHandlerRegistration(NewObjectKey(namespace, ""), cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			return matches(selector, obj)
+		},
+		Handler: handler,
+	})
+	if err := m.itemMonitor.attach(r); err != nil {
+		return nil, err
+	}
+	m.registrations[r] = struct{}{}
+	m.numHandlers.Add(1)
+	if m.synced {
+		r.markReady(nil)
+	}
+	klog.Info(s.resource.String(), " selector handler added", " selector key ", key)
+
+	return &selectorEventHandlerRegistration{
+		selectorKey:  key,
+		registration: r,
+	}, nil
+}
+
+// waitForMonitoredItem waits for the first sync of a monitoredItem started by AddEventHandler,
+// and marks its registrations as ready. On failure the informer is stopped and removed.
+func (s *selectorMonitor[T]) waitForMonitoredItem(ctx context.Context, key SelectorKey, m *monitoredItem) {
+	err := m.itemMonitor.WaitForSync(ctx, s.syncTimeout)
+	if err != nil {
+		m.itemMonitor.StopInformer()
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err != nil {
+		// the monitor may already have been removed
+		if s.monitors[key] == m {
+			delete(s.monitors, key)
+		}
+		klog.Error(s.resource.String(), " selector informer failed to sync", " selector key ", key, " err ", err)
+	} else {
+		m.synced = true
+		klog.Info(s.resource.String(), " selector informer started", " selector key ", key)
+	}
+
+	for r := range m.registrations {
+		r.markReady(err)
+	}
+}
+
+// RemoveEventHandler removes an event handler and stops the informer if no handlers are left.
+func (s *selectorMonitor[T]) RemoveEventHandler(handlerRegistration SelectorEventHandlerRegistration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if handlerRegistration == nil {
+		return fmt.Errorf("%s selector handler is nil", s.resource.String())
+	}
+	r, ok := handlerRegistration.(*selectorEventHandlerRegistration)
+	if !ok {
+		return fmt.Errorf("unexpected registration type: %T", handlerRegistration)
+	}
+	key := r.GetKey()
+
+	m, exists := s.monitors[key]
+	if !exists {
+		return fmt.Errorf("%s selector monitor already removed for selector key %v", s.resource.String(), key)
+	}
+
+	if err := m.itemMonitor.RemoveEventHandler(r.registration); err != nil {
+		return err
+	}
+	delete(m.registrations, r.registration)
+	r.registration.markReady(fmt.Errorf("%s selector handler removed before cache sync", s.resource.String()))
+	m.numHandlers.Add(-1)
+	klog.Info(s.resource.String(), " selector handler removed", " selector key ", key)
+
+	// stop informer if there is no handler
+	if m.numHandlers.Load() <= 0 {
+		if !m.itemMonitor.StopInformer() {
+			klog.Error(s.resource.String(), " selector informer already stopped", " selector key ", key)
+		}
+		delete(s.monitors, key)
+		klog.Info(s.resource.String(), " selector informer stopped", " selector key ", key)
+	}
+
+	return nil
+}
+
+// List returns the objects currently matching the selector from the informer's cache, sorted by name.
+// The informer sync is awaited without holding the lock, so that other selectors can be added,
+// removed and listed meanwhile.
+func (s *selectorMonitor[T]) List(handlerRegistration SelectorEventHandlerRegistration) ([]T, error) {
+	if handlerRegistration == nil {
+		return nil, fmt.Errorf("%s selector handler is nil", s.resource.String())
+	}
+	key := handlerRegistration.GetKey()
+
+	s.lock.RLock()
+	m, exists := s.monitors[key]
+	s.lock.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%s selector monitor doesn't exist for selector key %v", s.resource.String(), key)
+	}
+
+	selector, err := labels.Parse(key.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	// wait for informer store sync, to load objects
+	if err := waitForSync(context.Background(), m.itemMonitor.key, s.syncTimeout, handlerRegistration.HasSynced, m.itemMonitor.LastError); err != nil {
+		return nil, err
+	}
+
+	items := []T{}
+	for _, uncast := range m.itemMonitor.GetStore().List() {
+		if !matches(selector, uncast) {
+			continue
+		}
+		obj, ok := uncast.(T)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type: %T", uncast)
+		}
+		items = append(items, obj)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return metaName(items[i]) < metaName(items[j])
+	})
+
+	return items, nil
+}
+
+// metaName returns metadata.name of obj.
+func metaName(obj runtime.Object) string {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return accessor.GetName()
+}
@@ -0,0 +1,233 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func secretNames(secrets []*corev1.Secret) []string {
+	names := []string{}
+	for _, secret := range secrets {
+		names = append(names, secret.Name)
+	}
+	return names
+}
+
+func TestSecretSelectorEventHandler(t *testing.T) {
+	var (
+		namespace = "ns"
+		selected  = map[string]string{"router.openshift.io/cert": "true"}
+		selector  = labels.SelectorFromSet(selected)
+	)
+
+	fakeKubeClient := fake.NewSimpleClientset(
+		fakeLabelledSecret(namespace, "a", selected),
+		fakeLabelledSecret(namespace, "b", selected),
+		fakeLabelledSecret(namespace, "c", nil),
+	)
+	sm := fakeSecretMonitor(fakeKubeClient)
+
+	events := make(chan string, 10)
+	h, err := sm.AddSecretSelectorEventHandler(context.TODO(), namespace, selector, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			events <- "add " + obj.(*corev1.Secret).Name
+		},
+		DeleteFunc: func(obj interface{}) {
+			events <- "delete " + obj.(*corev1.Secret).Name
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectEvent := func(expected string) {
+		t.Helper()
+		select {
+		case got := <-events:
+			if got != expected {
+				t.Errorf("expected event %q got %q", expected, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for event %q", expected)
+		}
+	}
+	expectSecrets := func(expected []string) {
+		t.Helper()
+		secrets, err := sm.GetSecrets(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := secretNames(secrets); !reflect.DeepEqual(expected, got) {
+			t.Errorf("expected secrets %v got %v", expected, got)
+		}
+	}
+
+	// initial list, in any order
+	initial := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case got := <-events:
+			initial[got] = true
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for initial events")
+		}
+	}
+	if !reflect.DeepEqual(map[string]bool{"add a": true, "add b": true}, initial) {
+		t.Errorf("unexpected initial events %v", initial)
+	}
+	expectSecrets([]string{"a", "b"})
+
+	// secret enters the selection
+	if _, err := fakeKubeClient.CoreV1().Secrets(namespace).Update(context.TODO(), fakeLabelledSecret(namespace, "c", selected), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent("add c")
+	expectSecrets([]string{"a", "b", "c"})
+
+	// secret leaves the selection
+	if _, err := fakeKubeClient.CoreV1().Secrets(namespace).Update(context.TODO(), fakeLabelledSecret(namespace, "a", nil), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent("delete a")
+	expectSecrets([]string{"b", "c"})
+
+	if err := sm.RemoveSecretSelectorEventHandler(h); err != nil {
+		t.Error(err)
+	}
+	if len(sm.selectors.monitors) != 0 {
+		t.Error("selector monitor should be removed from map")
+	}
+	if _, err := sm.GetSecrets(h); err == nil {
+		t.Error("expecting an error, got nil")
+	}
+}
+
+func TestSecretSelectorEventHandlerAsync(t *testing.T) {
+	var (
+		namespace = "ns"
+		selected  = map[string]string{"speed": "fast"}
+		fast      = labels.SelectorFromSet(selected)
+		slow      = labels.SelectorFromSet(map[string]string{"speed": "slow"})
+		denied    = labels.SelectorFromSet(map[string]string{"speed": "denied"})
+	)
+
+	fakeKubeClient := fake.NewSimpleClientset(fakeLabelledSecret(namespace, "a", selected))
+	fakeKubeClient.PrependReactor("list", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.(clienttesting.ListAction).GetListRestrictions().Labels.String() == denied.String() {
+			return true, nil, apierrors.NewForbidden(corev1.Resource("secrets"), "", fmt.Errorf("denied"))
+		}
+		return false, nil, nil
+	})
+	// the list of the slow selector blocks until released
+	release := make(chan struct{})
+	sm := newSecretMonitor(fakeKubeClient)
+	sm.selectors.createInformer = func(namespace string, selector labels.Selector) cache.SharedInformer {
+		return cache.NewSharedInformer(&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if selector.String() == slow.String() {
+					<-release
+				}
+				options.LabelSelector = selector.String()
+				return fakeKubeClient.CoreV1().Secrets(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = selector.String()
+				return fakeKubeClient.CoreV1().Secrets(namespace).Watch(context.TODO(), options)
+			},
+		},
+			&corev1.Secret{},
+			0,
+		)
+	}
+
+	isReady := func(h SecretSelectorEventHandlerRegistration) bool {
+		select {
+		case <-h.Ready():
+			return true
+		case <-time.After(5 * time.Second):
+			return false
+		}
+	}
+
+	slowHandler, err := sm.AddSecretSelectorEventHandler(context.TODO(), namespace, slow, cache.ResourceEventHandlerFuncs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// listing the slow selector waits for its informer
+	got := make(chan error, 1)
+	go func() {
+		_, err := sm.GetSecrets(slowHandler)
+		got <- err
+	}()
+
+	// a slow informer, also when listed meanwhile, does not block other selectors
+	fastHandler, err := sm.AddSecretSelectorEventHandler(context.TODO(), namespace, fast, cache.ResourceEventHandlerFuncs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isReady(fastHandler) {
+		t.Fatal("fast registration should be ready")
+	}
+	if err := fastHandler.Err(); err != nil {
+		t.Fatal(err)
+	}
+	secrets, err := sm.GetSecrets(fastHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := secretNames(secrets); !reflect.DeepEqual(names, []string{"a"}) {
+		t.Errorf("expected secrets [a], got %v", names)
+	}
+	select {
+	case <-slowHandler.Ready():
+		t.Fatal("slow registration should not be ready before its list returns")
+	default:
+	}
+
+	close(release)
+	if !isReady(slowHandler) {
+		t.Fatal("slow registration should be ready")
+	}
+	if err := slowHandler.Err(); err != nil {
+		t.Error(err)
+	}
+	if err := <-got; err != nil {
+		t.Error(err)
+	}
+
+	// a failed sync is reported through Err, and the informer is removed
+	deniedHandler, err := sm.AddSecretSelectorEventHandler(context.TODO(), namespace, denied, cache.ResourceEventHandlerFuncs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isReady(deniedHandler) {
+		t.Fatal("denied registration should be ready")
+	}
+	if !IsSyncForbidden(deniedHandler.Err()) {
+		t.Errorf("expected forbidden sync error, got %v", deniedHandler.Err())
+	}
+	sm.selectors.lock.RLock()
+	if _, exists := sm.selectors.monitors[NewSelectorKey(namespace, denied)]; exists {
+		t.Error("failed informer should be removed")
+	}
+	sm.selectors.lock.RUnlock()
+
+	for _, h := range []SecretSelectorEventHandlerRegistration{slowHandler, fastHandler} {
+		if err := sm.RemoveSecretSelectorEventHandler(h); err != nil {
+			t.Error(err)
+		}
+	}
+}
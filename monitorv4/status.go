@@ -0,0 +1,160 @@
+package secret
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// MonitorStatus reports the health of the informer monitoring an object.
+type MonitorStatus struct {
+	// Key is the key of the monitored object. The name is empty for namespace-wide informers.
+	Key ObjectKey
+	// Synced is true if the informer's cache has synced.
+	Synced bool
+	// LastError is the last list/watch error reported by the informer, e.g. forbidden or expired.
+	LastError error
+	// LastErrorTime is when LastError was reported.
+	LastErrorTime time.Time
+	// ConsecutiveFailures is the number of list/watch errors since the last successful list or watch,
+	// or the last event received.
+	ConsecutiveFailures int
+	// LastEventTime is when the informer last delivered an event.
+	LastEventTime time.Time
+	// NumHandlers is the number of handlers registered with the informer,
+	// which is shared by all registrations of the object, or of the namespace.
+	NumHandlers int
+}
+
+// Degraded returns true if the informer failed to list/watch since it last succeeded, or delivered an event.
+func (s MonitorStatus) Degraded() bool {
+	return s.ConsecutiveFailures > 0
+}
+
+// statusHandler records the time of the events delivered by the informer of a singleItemMonitor.
+type statusHandler struct {
+	monitor *singleItemMonitor
+}
+
+func (h statusHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	h.monitor.recordEvent()
+}
+
+func (h statusHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.monitor.recordEvent()
+}
+
+func (h statusHandler) OnDelete(obj interface{}) {
+	h.monitor.recordEvent()
+}
+
+var _ cache.ResourceEventHandler = statusHandler{}
+
+// observedListWatch is a ListerWatcher reporting its successful lists and watches to the singleItemMonitor
+// observing its informer. A recovered list/watch of a missing object delivers no event to statusHandler.
+type observedListWatch struct {
+	cache.ListerWatcher
+
+	lock      sync.RWMutex
+	onSuccess func()
+}
+
+// reportTo makes the ListerWatcher call onSuccess after each successful list or watch.
+func (l *observedListWatch) reportTo(onSuccess func()) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.onSuccess = onSuccess
+}
+
+func (l *observedListWatch) succeeded() {
+	l.lock.RLock()
+	onSuccess := l.onSuccess
+	l.lock.RUnlock()
+	if onSuccess != nil {
+		onSuccess()
+	}
+}
+
+func (l *observedListWatch) List(options metav1.ListOptions) (runtime.Object, error) {
+	list, err := l.ListerWatcher.List(options)
+	if err == nil {
+		l.succeeded()
+	}
+	return list, err
+}
+
+func (l *observedListWatch) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	w, err := l.ListerWatcher.Watch(options)
+	if err == nil {
+		l.succeeded()
+	}
+	return w, err
+}
+
+// observedInformer is a SharedInformer listing and watching through an observedListWatch.
+type observedInformer struct {
+	cache.SharedInformer
+	listWatch *observedListWatch
+}
+
+// newObservedInformer creates a SharedInformer for the objects listed and watched by lw,
+// whose successful lists and watches are reported to the singleItemMonitor observing it.
+func newObservedInformer(lw cache.ListerWatcher, exampleObject runtime.Object) cache.SharedInformer {
+	listWatch := &observedListWatch{ListerWatcher: lw}
+	return &observedInformer{
+		SharedInformer: cache.NewSharedInformer(listWatch, exampleObject, 0),
+		listWatch:      listWatch,
+	}
+}
+
+// Status returns the health of the informer which currently monitors the object with the given key.
+// Objects monitored by a namespace-wide informer report the status of that informer.
+func (o *objectMonitor[T]) Status(key ObjectKey) (MonitorStatus, error) {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	itemMonitor, _, exists := o.itemGetter(key)
+	if !exists {
+		return MonitorStatus{}, fmt.Errorf("%s monitor doesn't exist for key %v", o.resource.String(), key)
+	}
+	status := itemMonitor.Status()
+	status.Key = key
+	if m, exists := o.monitors[key]; exists {
+		status.NumHandlers = int(m.numHandlers.Load())
+	} else {
+		status.NumHandlers = o.namespaces[key.Namespace].numHandlers()
+	}
+	return status, nil
+}
+
+// StatusAll returns the health of all informers, sorted by key.
+// Namespace-wide informers are reported with an empty name.
+func (o *objectMonitor[T]) StatusAll() []MonitorStatus {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	statuses := make([]MonitorStatus, 0, len(o.monitors)+len(o.namespaces))
+	for _, m := range o.monitors {
+		status := m.itemMonitor.Status()
+		status.NumHandlers = int(m.numHandlers.Load())
+		statuses = append(statuses, status)
+	}
+	for _, n := range o.namespaces {
+		status := n.itemMonitor.Status()
+		status.NumHandlers = n.numHandlers()
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Key.Namespace != statuses[j].Key.Namespace {
+			return statuses[i].Key.Namespace < statuses[j].Key.Namespace
+		}
+		return statuses[i].Key.Name < statuses[j].Key.Name
+	})
+	return statuses
+}
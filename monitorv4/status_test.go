@@ -0,0 +1,105 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestStatus(t *testing.T) {
+	var (
+		namespace  = "ns"
+		secretName = "secret"
+		key        = NewObjectKey(namespace, secretName)
+	)
+
+	scenarios := []struct {
+		name    string
+		missing bool
+		listErr error
+		// listFailures is the number of lists failing with listErr, all of them if zero
+		listFailures   int32
+		expectSynced   bool
+		expectDegraded bool
+	}{
+		{
+			name:         "healthy secret watch",
+			expectSynced: true,
+		},
+		{
+			name:           "list keeps failing",
+			listErr:        apierrors.NewInternalError(fmt.Errorf("etcd is down")),
+			expectDegraded: true,
+		},
+		{
+			name:         "list of a missing secret recovers without any event",
+			missing:      true,
+			listErr:      apierrors.NewInternalError(fmt.Errorf("etcd is down")),
+			listFailures: 1,
+			expectSynced: true,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			fakeKubeClient := fake.NewSimpleClientset()
+			if !s.missing {
+				fakeKubeClient = fake.NewSimpleClientset(fakeSecret(namespace, secretName))
+			}
+			if s.listErr != nil {
+				var lists atomic.Int32
+				fakeKubeClient.PrependReactor("list", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+					if s.listFailures > 0 && lists.Add(1) > s.listFailures {
+						return false, nil, nil
+					}
+					return true, nil, s.listErr
+				})
+			}
+			// the informers of the monitor report their successful lists and watches
+			sm := newSecretMonitor(fakeKubeClient)
+
+			if _, err := sm.Status(key); err == nil {
+				t.Error("expecting an error for a secret which is not monitored")
+			}
+
+			h, err := sm.AddSecretEventHandlerAsync(context.TODO(), namespace, secretName, cache.ResourceEventHandlerFuncs{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sm.RemoveSecretEventHandler(h)
+
+			var status MonitorStatus
+			if err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+				status, err = sm.Status(key)
+				if err != nil {
+					return false, err
+				}
+				return status.Synced == s.expectSynced && status.Degraded() == s.expectDegraded, nil
+			}); err != nil {
+				t.Fatalf("unexpected status %+v: %v", status, err)
+			}
+
+			if status.Key != key {
+				t.Errorf("expected key %v, got %v", key, status.Key)
+			}
+			if s.listErr != nil && status.LastError == nil {
+				t.Error("expected last error of failed list")
+			}
+			if !s.expectDegraded && !s.missing && status.LastEventTime.IsZero() {
+				t.Error("expected last event time of healthy watch")
+			}
+			if all := sm.StatusAll(); len(all) != 1 || all[0].Key != key {
+				t.Errorf("expected status of %v only, got %+v", key, all)
+			}
+		})
+	}
+}
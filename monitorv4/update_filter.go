@@ -0,0 +1,128 @@
+package secret
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"sort"
+	"sync/atomic"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// UpdateFilter selects the content of a secret which is compared to tell apart updates
+// changing the secret from no-op updates, e.g. resyncs or managedFields churn.
+// The type and data of the secret are always compared.
+type UpdateFilter struct {
+	// Labels are the keys of the labels which are compared as well.
+	Labels []string
+	// Annotations are the keys of the annotations which are compared as well.
+	Annotations []string
+}
+
+// updateFilter drops the updates of secrets whose compared content did not change,
+// and counts the dropped updates.
+type updateFilter struct {
+	UpdateFilter
+	dropped atomic.Int64
+}
+
+// contentHash returns the hash of the compared content of the secret.
+func (f *updateFilter) contentHash(secret *v1.Secret) [sha256.Size]byte {
+	h := sha256.New()
+	writeString(h, string(secret.Type))
+	writeMap(h, secret.Data, nil)
+	writeMap(h, nil, selected(secret.Labels, f.Labels))
+	writeMap(h, nil, selected(secret.Annotations, f.Annotations))
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// selected returns the values of m with the given keys.
+func selected(m map[string]string, keys []string) map[string]string {
+	ret := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if value, exists := m[key]; exists {
+			ret[key] = value
+		}
+	}
+	return ret
+}
+
+// writeLength writes n to h.
+func writeLength(h hash.Hash, n int) {
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(n))
+	h.Write(length[:])
+}
+
+// writeString writes s to h, prefixed by its length so that consecutive strings can't be confused.
+func writeString(h hash.Hash, s string) {
+	writeLength(h, len(s))
+	h.Write([]byte(s))
+}
+
+// writeMap writes the entries of data and strings to h, sorted by key.
+func writeMap(h hash.Hash, data map[string][]byte, strings map[string]string) {
+	keys := make([]string, 0, len(data)+len(strings))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	for key := range strings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	writeLength(h, len(keys))
+	for _, key := range keys {
+		writeString(h, key)
+		if value, exists := data[key]; exists {
+			writeString(h, string(value))
+		} else {
+			writeString(h, strings[key])
+		}
+	}
+}
+
+// unchanged returns true if the update did not change the compared content of the secret.
+func (f *updateFilter) unchanged(oldObj, newObj interface{}) bool {
+	oldSecret, ok := oldObj.(*v1.Secret)
+	if !ok {
+		return false
+	}
+	newSecret, ok := newObj.(*v1.Secret)
+	if !ok {
+		return false
+	}
+	return f.contentHash(oldSecret) == f.contentHash(newSecret)
+}
+
+// wrap returns a handler which notifies handler about all events, except no-op updates.
+func (f *updateFilter) wrap(handler cache.ResourceEventHandler) cache.ResourceEventHandler {
+	return filteringHandler{filter: f, handler: handler}
+}
+
+// filteringHandler forwards the events to handler, dropping no-op updates.
+type filteringHandler struct {
+	filter  *updateFilter
+	handler cache.ResourceEventHandler
+}
+
+func (h filteringHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	h.handler.OnAdd(obj, isInInitialList)
+}
+
+func (h filteringHandler) OnUpdate(oldObj, newObj interface{}) {
+	if h.filter.unchanged(oldObj, newObj) {
+		h.filter.dropped.Add(1)
+		return
+	}
+	h.handler.OnUpdate(oldObj, newObj)
+}
+
+func (h filteringHandler) OnDelete(obj interface{}) {
+	h.handler.OnDelete(obj)
+}
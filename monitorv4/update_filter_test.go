@@ -0,0 +1,146 @@
+package secret
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestUpdateFilter(t *testing.T) {
+	namespace := "sandbox"
+
+	scenarios := []struct {
+		name          string
+		filter        UpdateFilter
+		update        func(*corev1.Secret)
+		expectDropped bool
+	}{
+		{
+			name:          "resync is dropped",
+			update:        func(s *corev1.Secret) {},
+			expectDropped: true,
+		},
+		{
+			name: "managedFields churn is dropped",
+			update: func(s *corev1.Secret) {
+				s.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kubectl"}}
+				s.ResourceVersion = "2"
+			},
+			expectDropped: true,
+		},
+		{
+			name: "data change is forwarded",
+			update: func(s *corev1.Secret) {
+				s.Data["test"] = []byte{5}
+			},
+		},
+		{
+			name: "new data key is forwarded",
+			update: func(s *corev1.Secret) {
+				s.Data["tls.crt"] = []byte{}
+			},
+		},
+		{
+			name: "type change is forwarded",
+			update: func(s *corev1.Secret) {
+				s.Type = corev1.SecretTypeTLS
+			},
+		},
+		{
+			name: "annotation change is dropped unless opted in",
+			update: func(s *corev1.Secret) {
+				s.Annotations = map[string]string{"cert-manager.io/issuer-name": "ca"}
+			},
+			expectDropped: true,
+		},
+		{
+			name:   "opted in annotation change is forwarded",
+			filter: UpdateFilter{Annotations: []string{"cert-manager.io/issuer-name"}},
+			update: func(s *corev1.Secret) {
+				s.Annotations = map[string]string{"cert-manager.io/issuer-name": "ca"}
+			},
+		},
+		{
+			name:   "opted in label change is forwarded",
+			filter: UpdateFilter{Labels: []string{"app"}},
+			update: func(s *corev1.Secret) {
+				s.Labels = map[string]string{"app": "frontend"}
+			},
+		},
+		{
+			name:   "other label change is dropped",
+			filter: UpdateFilter{Labels: []string{"app"}},
+			update: func(s *corev1.Secret) {
+				s.Labels = map[string]string{"team": "router"}
+			},
+			expectDropped: true,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			f := &updateFilter{UpdateFilter: s.filter}
+			recorder := &recordingHandler{}
+			h := f.wrap(recorder)
+
+			oldSecret := fakeSecret(namespace, "tls")
+			newSecret := oldSecret.DeepCopy()
+			s.update(newSecret)
+			h.OnUpdate(oldSecret, newSecret)
+
+			if dropped := len(recorder.get()) == 0; dropped != s.expectDropped {
+				t.Errorf("expected update to be dropped: %t", s.expectDropped)
+			}
+			if dropped := f.dropped.Load() == 1; dropped != s.expectDropped {
+				t.Errorf("expected %t, got %d dropped updates", s.expectDropped, f.dropped.Load())
+			}
+		})
+	}
+}
+
+func TestFilteringSecretMonitor(t *testing.T) {
+	namespace := "sandbox"
+
+	fakeKubeClient := fake.NewSimpleClientset(fakeSecretVersion(namespace, "tls", "1"))
+	sm := fakeSecretMonitor(fakeKubeClient).withUpdateFilter(UpdateFilter{})
+	recorder := &recordingHandler{}
+	r, err := sm.AddSecretEventHandler(context.TODO(), namespace, "tls", recorder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.RemoveSecretEventHandler(r)
+
+	// an annotation edit is dropped, a rotation is forwarded
+	annotated := fakeSecretVersion(namespace, "tls", "2")
+	annotated.Annotations = map[string]string{"edited": "true"}
+	rotated := fakeSecretVersion(namespace, "tls", "3")
+	rotated.Data["test"] = []byte{5}
+	for _, secret := range []*corev1.Secret{annotated, rotated} {
+		if _, err := fakeKubeClient.CoreV1().Secrets(namespace).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expect := []notification{
+		{eventType: watch.Added, newResourceVersion: "1"},
+		{eventType: watch.Modified, oldResourceVersion: "2", newResourceVersion: "3"},
+	}
+	err = wait.PollImmediate(10*time.Millisecond, 2*time.Second, func() (bool, error) {
+		return len(recorder.get()) >= len(expect), nil
+	})
+	if err != nil {
+		t.Fatalf("expected notifications %+v, got %+v", expect, recorder.get())
+	}
+	if got := recorder.get(); len(got) != len(expect) || got[0] != expect[0] || got[1] != expect[1] {
+		t.Errorf("expected notifications %+v, got %+v", expect, got)
+	}
+	if n := sm.DroppedUpdates(); n != 1 {
+		t.Errorf("expected 1 dropped update, got %d", n)
+	}
+}
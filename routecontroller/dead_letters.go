@@ -0,0 +1,75 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	secret "github.com/chiragkyal/watch-based-informer/monitorv4"
+)
+
+// DeadLetter is a route which failed to sync more than maxRetries times in a row. It is not retried
+// until the route or its secret changes again.
+type DeadLetter struct {
+	// Key is the key of the route.
+	Key secret.ParentKey
+	// Err is the error of the last sync.
+	Err error
+	// Failures is the number of failed syncs.
+	Failures int
+	// Time is when the route was given up.
+	Time time.Time
+}
+
+// deadLetters are the routes which were given up, keyed by route.
+type deadLetters struct {
+	lock    sync.RWMutex
+	letters map[secret.ParentKey]DeadLetter
+}
+
+func newDeadLetters() *deadLetters {
+	return &deadLetters{
+		letters: map[secret.ParentKey]DeadLetter{},
+	}
+}
+
+func (d *deadLetters) add(key secret.ParentKey, err error, failures int) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.letters[key] = DeadLetter{
+		Key:      key,
+		Err:      err,
+		Failures: failures,
+		Time:     time.Now(),
+	}
+}
+
+func (d *deadLetters) remove(key secret.ParentKey) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	delete(d.letters, key)
+}
+
+// list returns the dead letters, sorted by namespace and name of the route.
+func (d *deadLetters) list() []DeadLetter {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	letters := make([]DeadLetter, 0, len(d.letters))
+	for _, letter := range d.letters {
+		letters = append(letters, letter)
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		if letters[i].Key.Namespace != letters[j].Key.Namespace {
+			return letters[i].Key.Namespace < letters[j].Key.Namespace
+		}
+		return letters[i].Key.Name < letters[j].Key.Name
+	})
+	return letters
+}
+
+// DeadLetters returns the routes which the controller gave up syncing. A dead letter is removed
+// once the route syncs successfully after a later change of the route or its secret.
+func (c *Controller) DeadLetters() []DeadLetter {
+	return c.deadLetters.list()
+}
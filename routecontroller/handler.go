@@ -2,110 +2,92 @@ package main
 
 import (
 	"context"
+	"time"
 
 	secret "github.com/chiragkyal/watch-based-informer/monitorv4"
-	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/watch"
-	"k8s.io/client-go/tools/cache"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 )
 
-type Handler interface {
-	HandleRoute( /*eventType watch.EventType,route *v1.Pod*/ ) error
-	// GetResourceKey() string
-	// GetEventType() watch.EventType
-}
-
-type RouteEvent struct {
-	eventType watch.EventType
-	// resourceKey   string
-	route         *v1.ConfigMap
-	secretManager *secret.Manager
-}
-
-func NewRouteEvent(eventType watch.EventType, route *v1.ConfigMap, secretManager *secret.Manager) Handler {
-	return &RouteEvent{
-		eventType:     eventType,
-		route:         route,
-		secretManager: secretManager,
+// syncRoute brings the secret registration of the route with the given key in line with the route in
+// the informer cache. Whether the route was added, changed its secret reference, or was deleted, is
+// resolved here rather than when the event is enqueued, so that the queue only has to track keys.
+func (c *Controller) syncRoute(key secret.ParentKey) error {
+	obj, exists, err := c.indexer.GetByKey(key.Namespace + "/" + key.Name)
+	if err != nil {
+		klog.Errorf("Fetching object with key %v from store failed with %v", key, err)
+		return err
 	}
-}
-
-// func (re *RouteEvent) GetResourceKey() string {
-// 	return re.resourceKey
-// }
 
-// func (re *RouteEvent) GetEventType() watch.EventType {
-// 	return re.eventType
-// }
-
-func (re *RouteEvent) HandleRoute() error {
+	if !exists {
+		klog.Info("Route ", key, " does not exist anymore")
+		return c.unregisterRoute(key)
+	}
 
-	route := re.route
-	eventType := re.eventType
-	switch eventType {
+	route := getResource(obj)
+	secretName := getReferenceSecret(route)
+	if secretName == "" {
+		// nothing to watch for routes without an external certificate
+		return c.unregisterRoute(key)
+	}
 
-	case watch.Added:
-		err := localRegisterRoute(re.secretManager, route)
-		if err != nil {
-			klog.Error("failed to register route")
-			return err
-		}
+	// registers a new route, switches a route to its new secret, and keeps an unchanged reference.
+	// The secret events are enqueued as the route key by the Manager, so no handler has to capture the route.
+	err = c.secretManager.UpdateReference(context.Background(), route.Namespace, route.Name, secretName, c.secretManager.EnqueueParentsHandler())
+	if err != nil {
+		klog.Error("failed to register route ", key, ": ", err)
+	}
 
-	case watch.Modified:
-		// Update the route content to serve the certificate
-		// TODO: As you are getting the route Object from cache, it's content would be outdated, so need to resync before updating the certificate.
-		s, err := re.secretManager.GetSecret(route.Namespace, route.Name)
-		// TODO: what will happen if secret got deleted? should the route still serve the old certificate?
-		// Now err will return, secret not found
-		if err != nil {
-			klog.Error(err)
-			return err
-		}
-		klog.Info("fetching secret data ", s.Data)
+	// Update the route content to serve the certificate
+	var s *corev1.Secret
+	if err == nil {
+		s, err = c.secretManager.GetSecret(route.Namespace, route.Name)
+	}
 
-	case watch.Deleted:
-		err := re.secretManager.UnregisterRoute(route.Namespace, route.Name)
-		if err != nil {
-			klog.Error(err)
-			return err
+	// report why the secret can't be served in the route's status, and clear it once it can
+	condition := externalCertificateCondition(route, s, err)
+	if statusErr := c.updateExternalCertificateCondition(route, condition); statusErr != nil {
+		klog.Error("failed to update status of route ", key, ": ", statusErr)
+		if err == nil {
+			err = statusErr
 		}
-	default:
-		klog.Error("invalid eventType", eventType)
 	}
+	if err != nil {
+		klog.Error(err)
+		return err
+	}
+	if condition != nil {
+		// not retried; the route is enqueued again once the secret changes
+		klog.Info("not serving secret ", s.Name, " for route ", key, ": ", condition.Message)
+		return nil
+	}
+	klog.Info("serving secret ", s.Name, " resourceVersion ", s.ResourceVersion, " for route ", key)
+	c.warnIfExpiring(key)
 	return nil
 }
 
-func localRegisterRoute(secretManager *secret.Manager, route *v1.ConfigMap /*routev1.Route*/) error {
-	secreth := generateSecretHandler(secretManager, route)
-	secretManager.WithSecretHandler(secreth)
-	return secretManager.RegisterRoute(context.Background(), route.Namespace, route.Name, getReferenceSecret(route))
-
-}
-
-func generateSecretHandler(secretManager *secret.Manager, route *v1.ConfigMap /*routev1.Route*/) cache.ResourceEventHandlerFuncs {
-	// secret handler
-	secreth := cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			// key, _ := cache.MetaNamespaceKeyFunc(obj)
-			secret := obj.(*v1.Secret)
-			klog.Info("Secret added ", "obj ", secret.ResourceVersion, " key ", secret.Name, " For ", route.Namespace+"/"+route.Name)
-			secretManager.Queue().Add(NewRouteEvent(watch.Modified, route, secretManager))
-		},
-		UpdateFunc: func(old interface{}, new interface{}) {
-			// key, _ := cache.MetaNamespaceKeyFunc(new)
-			secretOld := old.(*v1.Secret)
-			secretNew := new.(*v1.Secret)
-			klog.Info("Secret updated ", "old ", secretOld.ResourceVersion, " new ", secretNew.ResourceVersion, " key ", secretNew.Name, " For ", route.Namespace+"/"+route.Name)
-			secretManager.Queue().Add(NewRouteEvent(watch.Modified, route, secretManager))
-		},
-		DeleteFunc: func(obj interface{}) {
-			// key, _ := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
-			secret := obj.(*v1.Secret)
-			klog.Info("Secret deleted ", " obj ", secret.ResourceVersion, " key ", secret.Name, " For ", route.Namespace+"/"+route.Name)
-			secretManager.Queue().Add(NewRouteEvent(watch.Modified, route, secretManager))
-		},
+// warnIfExpiring warns if the certificate served for the route expires within expiryWarningWindow.
+// The Manager requeues the route as the expiry approaches, so the warning is repeated until it is rotated.
+func (c *Controller) warnIfExpiring(key secret.ParentKey) {
+	registrations, err := c.secretManager.Describe(key)
+	if err != nil {
+		return
 	}
+	for _, r := range registrations {
+		if r.NotAfter.IsZero() {
+			continue
+		}
+		if remaining := time.Until(r.NotAfter); remaining <= 0 {
+			klog.Warning("certificate of secret ", r.Secret, " for route ", key, " expired at ", r.NotAfter)
+		} else if remaining < expiryWarningWindow {
+			klog.Warning("certificate of secret ", r.Secret, " for route ", key, " expires at ", r.NotAfter)
+		}
+	}
+}
 
-	return secreth
+// unregisterRoute stops watching the secret of the route, if any.
+func (c *Controller) unregisterRoute(key secret.ParentKey) error {
+	// an empty set unregisters the route, and is a no-op for routes which are not registered
+	return c.secretManager.RegisterRouteSecrets(context.Background(), key.Namespace, key.Name, "", sets.NewString(), nil)
 }
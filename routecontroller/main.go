@@ -8,33 +8,52 @@ import (
 	"k8s.io/klog/v2"
 
 	secret "github.com/chiragkyal/watch-based-informer/monitorv4"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/workqueue"
-	// routev1 "github.com/openshift/api/route/v1"
-	// routev1client "github.com/openshift/client-go/route/clientset/versioned"
+
+	routev1client "github.com/openshift/client-go/route/clientset/versioned"
 )
 
 var namespace = "sandbox"
 
+// maxRetries is the default number of retries of a failing route.
+const maxRetries = 5
+
+// expiryWarningWindow is how long before the expiry of a served certificate the controller starts warning.
+const expiryWarningWindow = 30 * 24 * time.Hour
+
 // Controller demonstrates how to implement a controller with client-go.
 type Controller struct {
-	indexer  cache.Indexer
-	queue    workqueue.RateLimitingInterface
-	informer cache.Controller
+	indexer       cache.Indexer
+	queue         workqueue.RateLimitingInterface
+	informer      cache.Controller
+	secretManager *secret.Manager
+	routeClient   routev1client.Interface
+	// routerName is the name of the route.status.ingress entry the conditions are written to
+	routerName string
+
+	// maxRetries is the number of retries of a failing route before it is moved to deadLetters
+	maxRetries  int
+	deadLetters *deadLetters
 }
 
 // NewController creates a new Controller.
-func NewController(queue workqueue.RateLimitingInterface, indexer cache.Indexer, informer cache.Controller, clientset *kubernetes.Clientset /*handlerFuncs cache.ResourceEventHandlerFuncs*/) *Controller {
+func NewController(queue workqueue.RateLimitingInterface, indexer cache.Indexer, informer cache.Controller, secretManager *secret.Manager, routeClient routev1client.Interface) *Controller {
 
 	return &Controller{
-		informer: informer,
-		indexer:  indexer,
-		queue:    queue,
+		informer:      informer,
+		indexer:       indexer,
+		queue:         queue,
+		secretManager: secretManager,
+		routeClient:   routeClient,
+		routerName:    controllerRouterName,
+		maxRetries:    maxRetries,
+		deadLetters:   newDeadLetters(),
 	}
 }
 
@@ -44,71 +63,40 @@ func (c *Controller) processNextItem() bool {
 	if quit {
 		return false
 	}
+	// Tell the queue that we are done with processing this key. This unblocks the key for other workers
+	// This allows safe parallel processing because two routes with the same key are never processed in
+	// parallel.
 	defer c.queue.Done(item)
 
-	if _, ok := item.(Handler); ok {
-		klog.Info("successfully got item %v", item)
-		// return true
-	} else {
-		klog.Info("did not got item %v", item)
+	key, ok := item.(secret.ParentKey)
+	if !ok {
+		c.queue.Forget(item)
+		utilruntime.HandleError(fmt.Errorf("unexpected work item %v", item))
+		return true
 	}
 
 	// Invoke the method containing the business logic
-	err := c.syncToStdout(item.(Handler))
-	klog.Error(err)
-
+	err := c.syncRoute(key)
 	// Handle the error if something went wrong during the execution of the business logic
-	// 	TODO: invoke handle err
-	// c.handleErr(err, key)
+	c.handleErr(err, key)
 	return true
 }
 
-// syncToStdout is the business logic of the controller. In this controller it simply prints
-// information about the pod to stdout. In case an error happened, it has to simply return the error.
-// The retry logic should not be part of the business logic.
-func (c *Controller) syncToStdout(handler Handler) error {
-	// obj, exists, err := c.indexer.GetByKey(handler.GetResourceKey())
-	// if err != nil {
-	// 	klog.Errorf("Fetching object with key %s from store failed with %v", handler.GetResourceKey(), err)
-	// 	return err
-	// }
-
-	// if !exists {
-	// 	// Below we will warm up our cache with a Pod, so that we will see a delete for one pod
-	// 	klog.Info("Route %s does not exist anymore\n", handler.GetResourceKey())
-	// } else {
-	// 	// Note that you also have to check the uid if you have a local controlled resource, which
-	// 	// is dependent on the actual instance, to detect that a Pod was recreated with the same name
-	// 	if _, ok := obj.(*v1.Pod); !ok {
-	// 		klog.Error("unable to type cast to pod")
-	// 		return err
-	// 	}
-	// 	pod := obj.(*v1.Pod)
-
-	// 	klog.Info("Sync/Add/Update for Route %s\n", pod.GetName())
-
-	// 	// invoke actual logic
-	// 	if err := handler.HandleRoute( /*handler.GetEventType()*/ pod); err != nil {
-	// 		return err
-	// 	}
-	// }
-	// return nil
-	return handler.HandleRoute()
-}
-
 // handleErr checks if an error happened and makes sure we will retry later.
-func (c *Controller) handleErr(err error, key interface{}) {
+func (c *Controller) handleErr(err error, key secret.ParentKey) {
 	if err == nil {
 		// Forget about the #AddRateLimited history of the key on every successful synchronization.
 		// This ensures that future processing of updates for this key is not delayed because of
 		// an outdated error history.
 		c.queue.Forget(key)
+		c.deadLetters.remove(key)
 		return
 	}
 
-	// This controller retries 5 times if something goes wrong. After that, it stops trying.
-	if c.queue.NumRequeues(key) < 5 {
-		klog.Infof("Error syncing pod %v: %v", key, err)
+	// This controller retries maxRetries times if something goes wrong. After that, it stops trying.
+	failures := c.queue.NumRequeues(key) + 1
+	if failures <= c.maxRetries {
+		klog.Infof("Error syncing route %v: %v", key, err)
 
 		// Re-enqueue the key rate limited. Based on the rate limiter on the
 		// queue and the re-enqueue history, the key will be processed later again.
@@ -119,7 +107,8 @@ func (c *Controller) handleErr(err error, key interface{}) {
 	c.queue.Forget(key)
 	// Report to an external entity that, even after several retries, we could not successfully process this key
 	utilruntime.HandleError(err)
-	klog.Infof("Dropping pod %q out of the queue: %v", key, err)
+	klog.Infof("Moving route %v to the dead-letter list: %v", key, err)
+	c.deadLetters.add(key, err, failures)
 }
 
 // Run begins watching and syncing.
@@ -151,31 +140,15 @@ func (c *Controller) runWorker() {
 	}
 }
 
-func main() {
-	var kubeconfig string
-	var master string
-
-	flag.StringVar(&kubeconfig, "kubeconfig", "", "absolute path to the kubeconfig file")
-	flag.StringVar(&master, "master", "", "master url")
-	flag.Parse()
-
-	// creates the connection
-	config, err := clientcmd.BuildConfigFromFlags(master, kubeconfig)
-	if err != nil {
-		klog.Fatal(err)
-	}
-
-	// creates the clientset
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		klog.Fatal(err)
-	}
-
-	routeListWatcher := getListWatcher(clientset, namespace)
+// newRouteController creates a Controller for the routes of the namespace, which watches the secrets
+// referenced by the routes' external certificates.
+func newRouteController(kubeClient kubernetes.Interface, routeClient routev1client.Interface, namespace string) *Controller {
+	routeListWatcher := getListWatcher(routeClient, namespace)
 
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 
-	secretManager := secret.NewManager(clientset, queue)
+	// the queue holds the route keys, which the expiry requeues add as well
+	secretManager := secret.NewManager(kubeClient, queue).WithExpiryThresholds(secret.DefaultExpiryThresholds...)
 
 	// Route handler
 	routeh := cache.ResourceEventHandlerFuncs{
@@ -187,7 +160,7 @@ func main() {
 			route := getResource(obj)
 			klog.Info("Add Event ", "route.Name ", route.Name, " key ", route.Name)
 
-			queue.Add(NewRouteEvent(watch.Added, route, secretManager))
+			queue.Add(secret.NewRouteKey(route.Namespace, route.Name))
 		},
 		UpdateFunc: func(old interface{}, new interface{}) {
 			// var oldKey, newKey string
@@ -201,12 +174,10 @@ func main() {
 			oldRoute := getResource(old)
 			newRoute := getResource(new)
 
-			if getReferenceSecret(oldRoute) != getReferenceSecret(newRoute) {
-				klog.Info("Roue Update event ", "old ", oldRoute.ResourceVersion, " new ", newRoute.ResourceVersion, " newkey ", newRoute.Name, " oldKey ", oldRoute.Name)
-				// remove old watch
-				queue.Add(NewRouteEvent(watch.Deleted, oldRoute, secretManager))
-				// create new watch
-				queue.Add(NewRouteEvent(watch.Added, newRoute, secretManager))
+			// status updates don't change what the route is served
+			if !apiequality.Semantic.DeepEqual(oldRoute.Spec, newRoute.Spec) {
+				klog.Info("Route Update event ", "old ", oldRoute.ResourceVersion, " new ", newRoute.ResourceVersion, " newkey ", newRoute.Name, " oldKey ", oldRoute.Name)
+				queue.Add(secret.NewRouteKey(newRoute.Namespace, newRoute.Name))
 			}
 		},
 		DeleteFunc: func(obj interface{}) {
@@ -218,7 +189,7 @@ func main() {
 			klog.Info("Delete event ", " obj ", route.ResourceVersion, " key ", route.Name)
 
 			// when route is deleted, remove associated secret watcher
-			queue.Add(NewRouteEvent(watch.Deleted, route, secretManager))
+			queue.Add(secret.NewRouteKey(route.Namespace, route.Name))
 
 		},
 	}
@@ -226,7 +197,35 @@ func main() {
 	// Route Controller
 	indexer, informer := cache.NewIndexerInformer(routeListWatcher, emptyResource(), 0, routeh, cache.Indexers{})
 
-	controller := NewController(queue, indexer, informer, clientset)
+	return NewController(queue, indexer, informer, secretManager, routeClient)
+}
+
+func main() {
+	var kubeconfig string
+	var master string
+
+	flag.StringVar(&kubeconfig, "kubeconfig", "", "absolute path to the kubeconfig file")
+	flag.StringVar(&master, "master", "", "master url")
+	flag.Parse()
+
+	// creates the connection
+	config, err := clientcmd.BuildConfigFromFlags(master, kubeconfig)
+	if err != nil {
+		klog.Fatal(err)
+	}
+
+	// creates the clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		klog.Fatal(err)
+	}
+
+	routeClient, err := routev1client.NewForConfig(config)
+	if err != nil {
+		klog.Fatal(err)
+	}
+
+	controller := newRouteController(clientset, routeClient, namespace)
 
 	// Now let's start the controller
 	stop := make(chan struct{})
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	secret "github.com/chiragkyal/watch-based-informer/monitorv4"
+	routev1 "github.com/openshift/api/route/v1"
+	fakeroutev1client "github.com/openshift/client-go/route/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func fakeRoute(namespace, name, secretName string) *routev1.Route {
+	route := &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: routev1.RouteSpec{
+			Host: name + ".example.com",
+		},
+	}
+	if secretName != "" {
+		route.Spec.TLS = &routev1.TLSConfig{
+			Termination:         routev1.TLSTerminationEdge,
+			ExternalCertificate: routev1.LocalObjectReference{Name: secretName},
+		}
+	}
+	return route
+}
+
+// testCertificate and testPrivateKey are a self-signed certificate covering the hosts of the fake routes.
+var testCertificate, testPrivateKey = generateCertificate("*.example.com")
+
+// generateCertificate returns a PEM encoded self-signed certificate for dnsName, and its private key.
+func generateCertificate(dnsName string) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		panic(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func fakeSecret(namespace, name string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       testCertificate,
+			corev1.TLSPrivateKeyKey: testPrivateKey,
+		},
+	}
+}
+
+// waitForRouteSecret waits until the route is served the secret with the given name,
+// or is not registered if secretName is empty.
+func waitForRouteSecret(t *testing.T, c *Controller, namespace, routeName, secretName string) {
+	t.Helper()
+	err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		s, err := c.secretManager.GetSecret(namespace, routeName)
+		if secretName == "" {
+			return err != nil, nil
+		}
+		return err == nil && s.Name == secretName, nil
+	})
+	if err != nil {
+		t.Fatalf("expected route %s/%s to be served secret %q", namespace, routeName, secretName)
+	}
+}
+
+func TestRouteController(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		routeName = "route"
+	)
+
+	scenarios := []struct {
+		name         string
+		initial      string
+		updated      string
+		expectSecret string
+	}{
+		{
+			name:         "route switches to the new external certificate",
+			initial:      "tls",
+			updated:      "rotated",
+			expectSecret: "rotated",
+		},
+		{
+			name:         "route dropping its external certificate is unregistered",
+			initial:      "tls",
+			updated:      "",
+			expectSecret: "",
+		},
+		{
+			name:         "route adding an external certificate is registered",
+			initial:      "",
+			updated:      "tls",
+			expectSecret: "tls",
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			fakeKubeClient := fake.NewSimpleClientset(fakeSecret(namespace, "tls"), fakeSecret(namespace, "rotated"))
+			fakeRouteClient := fakeroutev1client.NewSimpleClientset(fakeRoute(namespace, routeName, s.initial))
+			c := newRouteController(fakeKubeClient, fakeRouteClient, namespace)
+
+			stop := make(chan struct{})
+			defer close(stop)
+			go c.Run(1, stop)
+
+			waitForRouteSecret(t, c, namespace, routeName, s.initial)
+
+			if _, err := fakeRouteClient.RouteV1().Routes(namespace).Update(context.TODO(), fakeRoute(namespace, routeName, s.updated), metav1.UpdateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			waitForRouteSecret(t, c, namespace, routeName, s.expectSecret)
+
+			if err := fakeRouteClient.RouteV1().Routes(namespace).Delete(context.TODO(), routeName, metav1.DeleteOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			waitForRouteSecret(t, c, namespace, routeName, "")
+		})
+	}
+}
+
+func TestRouteControllerDeadLetters(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		routeName = "route"
+		key       = secret.NewRouteKey(namespace, routeName)
+	)
+
+	// the referenced secret doesn't exist yet, so every sync fails
+	fakeKubeClient := fake.NewSimpleClientset()
+	fakeRouteClient := fakeroutev1client.NewSimpleClientset(fakeRoute(namespace, routeName, "tls"))
+	c := newRouteController(fakeKubeClient, fakeRouteClient, namespace)
+	c.maxRetries = 2
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go c.Run(1, stop)
+
+	err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return len(c.DeadLetters()) == 1, nil
+	})
+	if err != nil {
+		t.Fatalf("expected route to be moved to the dead-letter list, got %v", c.DeadLetters())
+	}
+	letter := c.DeadLetters()[0]
+	if letter.Key != key || letter.Failures != c.maxRetries+1 || !apierrors.IsNotFound(letter.Err) {
+		t.Errorf("unexpected dead letter %+v", letter)
+	}
+	if n := c.queue.NumRequeues(key); n != 0 {
+		t.Errorf("expected the retries of a dead letter to be forgotten, got %d", n)
+	}
+
+	// the secret showing up enqueues the route again
+	if _, err := fakeKubeClient.CoreV1().Secrets(namespace).Create(context.TODO(), fakeSecret(namespace, "tls"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForRouteSecret(t, c, namespace, routeName, "tls")
+	err = wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return len(c.DeadLetters()) == 0, nil
+	})
+	if err != nil {
+		t.Errorf("expected recovered route to leave the dead-letter list, got %v", c.DeadLetters())
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+
+	secret "github.com/chiragkyal/watch-based-informer/monitorv4"
+	"github.com/chiragkyal/watch-based-informer/validation"
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// controllerRouterName is the name of the route.status.ingress entry the controller writes its conditions to.
+// It must not be the name of a router, such as "default", whose entry would be overwritten by the router.
+const controllerRouterName = "route-secret-controller"
+
+// externalCertificateValid reports whether the secret referenced by route.Spec.TLS.ExternalCertificate
+// can be served. It is only set while the secret can't be served, with one of the reasons below.
+const externalCertificateValid routev1.RouteIngressConditionType = "ExternalCertificateValid"
+
+// Reasons of the externalCertificateValid condition, besides the validation.Reason of an invalid secret.
+const (
+	reasonSecretNotFound   = "SecretNotFound"
+	reasonSecretForbidden  = "SecretForbidden"
+	reasonSecretSyncFailed = "SecretSyncFailed"
+)
+
+// externalCertificateCondition returns the condition telling why the secret can't be served for the route,
+// given the secret or the error fetching it. Nil if the secret can be served.
+func externalCertificateCondition(route *routev1.Route, s *corev1.Secret, err error) *routev1.RouteIngressCondition {
+	if err == nil {
+		err = validation.ValidateRouteSecret(s, route)
+	}
+
+	var reason string
+	switch {
+	case err == nil:
+		return nil
+	case validation.ReasonFor(err) != "":
+		reason = string(validation.ReasonFor(err))
+	case apierrors.IsNotFound(err):
+		reason = reasonSecretNotFound
+	case apierrors.IsForbidden(err), secret.IsSyncForbidden(err):
+		reason = reasonSecretForbidden
+	default:
+		reason = reasonSecretSyncFailed
+	}
+	return &routev1.RouteIngressCondition{
+		Type:    externalCertificateValid,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: err.Error(),
+	}
+}
+
+// updateExternalCertificateCondition writes condition to the route's status, or clears the condition if nil.
+// Nothing is written if the cached route already carries the condition. Otherwise the route is re-read and
+// the update retried on conflicts. A deleted route is ignored.
+func (c *Controller) updateExternalCertificateCondition(cached *routev1.Route, condition *routev1.RouteIngressCondition) error {
+	if !setIngressCondition(cached.DeepCopy(), c.routerName, condition) {
+		return nil
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		route, err := c.routeClient.RouteV1().Routes(cached.Namespace).Get(context.TODO(), cached.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		route = route.DeepCopy()
+		if !setIngressCondition(route, c.routerName, condition) {
+			return nil
+		}
+		_, err = c.routeClient.RouteV1().Routes(cached.Namespace).UpdateStatus(context.TODO(), route, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// setIngressCondition sets the condition of the ingress entry of routerName, creating the entry if needed,
+// or removes the externalCertificateValid condition if condition is nil. The transition time is only
+// changed if the status changes. Returns false if the route was left unchanged.
+func setIngressCondition(route *routev1.Route, routerName string, condition *routev1.RouteIngressCondition) bool {
+	index := -1
+	for i := range route.Status.Ingress {
+		if route.Status.Ingress[i].RouterName == routerName {
+			index = i
+			break
+		}
+	}
+
+	if condition == nil {
+		if index < 0 {
+			return false
+		}
+		ingress := &route.Status.Ingress[index]
+		conditions := make([]routev1.RouteIngressCondition, 0, len(ingress.Conditions))
+		for _, existing := range ingress.Conditions {
+			if existing.Type != externalCertificateValid {
+				conditions = append(conditions, existing)
+			}
+		}
+		if len(conditions) == len(ingress.Conditions) {
+			return false
+		}
+		ingress.Conditions = conditions
+		// drop the entry if it only carried the condition
+		if len(conditions) == 0 {
+			route.Status.Ingress = append(route.Status.Ingress[:index], route.Status.Ingress[index+1:]...)
+		}
+		return true
+	}
+
+	if index < 0 {
+		route.Status.Ingress = append(route.Status.Ingress, routev1.RouteIngress{
+			Host:           route.Spec.Host,
+			RouterName:     routerName,
+			WildcardPolicy: route.Spec.WildcardPolicy,
+		})
+		index = len(route.Status.Ingress) - 1
+	}
+	ingress := &route.Status.Ingress[index]
+
+	now := metav1.Now()
+	updated := *condition
+	updated.LastTransitionTime = &now
+	for i, existing := range ingress.Conditions {
+		if existing.Type != condition.Type {
+			continue
+		}
+		if existing.Status == condition.Status && existing.Reason == condition.Reason && existing.Message == condition.Message {
+			return false
+		}
+		if existing.Status == condition.Status {
+			updated.LastTransitionTime = existing.LastTransitionTime
+		}
+		ingress.Conditions[i] = updated
+		return true
+	}
+	ingress.Conditions = append(ingress.Conditions, updated)
+	return true
+}
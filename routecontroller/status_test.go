@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/chiragkyal/watch-based-informer/validation"
+	routev1 "github.com/openshift/api/route/v1"
+	fakeroutev1client "github.com/openshift/client-go/route/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// externalCertificateReason returns the reason of the externalCertificateValid condition of the route,
+// or "" if it is not set.
+func externalCertificateReason(route *routev1.Route) string {
+	for _, ingress := range route.Status.Ingress {
+		if ingress.RouterName != controllerRouterName {
+			continue
+		}
+		for _, condition := range ingress.Conditions {
+			if condition.Type == externalCertificateValid && condition.Status == corev1.ConditionFalse {
+				return condition.Reason
+			}
+		}
+	}
+	return ""
+}
+
+func TestSetIngressCondition(t *testing.T) {
+	admitted := routev1.RouteIngressCondition{Type: routev1.RouteAdmitted, Status: corev1.ConditionTrue}
+	transition := metav1.NewTime(time.Now().Add(-time.Hour))
+	notFound := routev1.RouteIngressCondition{Type: externalCertificateValid, Status: corev1.ConditionFalse, Reason: reasonSecretNotFound, Message: "not found", LastTransitionTime: &transition}
+
+	scenarios := []struct {
+		name             string
+		ingress          []routev1.RouteIngress
+		condition        *routev1.RouteIngressCondition
+		expectChanged    bool
+		expectConditions []routev1.RouteIngressConditionType
+		expectTransition bool
+	}{
+		{
+			name:             "condition is added next to the router's conditions",
+			ingress:          []routev1.RouteIngress{{RouterName: controllerRouterName, Conditions: []routev1.RouteIngressCondition{admitted}}},
+			condition:        &notFound,
+			expectChanged:    true,
+			expectConditions: []routev1.RouteIngressConditionType{routev1.RouteAdmitted, externalCertificateValid},
+			expectTransition: true,
+		},
+		{
+			name:             "unchanged condition is not written",
+			ingress:          []routev1.RouteIngress{{RouterName: controllerRouterName, Conditions: []routev1.RouteIngressCondition{notFound}}},
+			condition:        &notFound,
+			expectConditions: []routev1.RouteIngressConditionType{externalCertificateValid},
+		},
+		{
+			name:             "new reason with the same status keeps the transition time",
+			ingress:          []routev1.RouteIngress{{RouterName: controllerRouterName, Conditions: []routev1.RouteIngressCondition{notFound}}},
+			condition:        &routev1.RouteIngressCondition{Type: externalCertificateValid, Status: corev1.ConditionFalse, Reason: reasonSecretForbidden, Message: "forbidden"},
+			expectChanged:    true,
+			expectConditions: []routev1.RouteIngressConditionType{externalCertificateValid},
+		},
+		{
+			name:             "recovery clears the condition only",
+			ingress:          []routev1.RouteIngress{{RouterName: controllerRouterName, Conditions: []routev1.RouteIngressCondition{admitted, notFound}}},
+			expectChanged:    true,
+			expectConditions: []routev1.RouteIngressConditionType{routev1.RouteAdmitted},
+		},
+		{
+			name:          "recovery drops the entry which only carried the condition",
+			ingress:       []routev1.RouteIngress{{RouterName: controllerRouterName, Conditions: []routev1.RouteIngressCondition{notFound}}},
+			expectChanged: true,
+		},
+		{
+			name: "route without the condition is not written",
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			route := fakeRoute("sandbox", "route", "tls")
+			route.Status.Ingress = s.ingress
+
+			if changed := setIngressCondition(route, controllerRouterName, s.condition); changed != s.expectChanged {
+				t.Errorf("expected changed %t, got %t", s.expectChanged, changed)
+			}
+
+			var got []routev1.RouteIngressConditionType
+			for _, ingress := range route.Status.Ingress {
+				for _, condition := range ingress.Conditions {
+					got = append(got, condition.Type)
+					if condition.Type == externalCertificateValid && s.condition != nil {
+						if transitioned := !condition.LastTransitionTime.Equal(&transition); transitioned != s.expectTransition {
+							t.Errorf("expected transition time to change: %t", s.expectTransition)
+						}
+					}
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(s.expectConditions) {
+				t.Errorf("expected conditions %v, got %v", s.expectConditions, got)
+			}
+		})
+	}
+}
+
+func TestUpdateExternalCertificateCondition(t *testing.T) {
+	notFound := routev1.RouteIngressCondition{Type: externalCertificateValid, Status: corev1.ConditionFalse, Reason: reasonSecretNotFound, Message: "not found"}
+
+	scenarios := []struct {
+		name      string
+		ingress   []routev1.RouteIngress
+		condition *routev1.RouteIngressCondition
+		// expectVerbs are the calls made to the route API
+		expectVerbs []string
+	}{
+		{
+			name:        "condition missing from the cached route is written",
+			condition:   &notFound,
+			expectVerbs: []string{"get", "update"},
+		},
+		{
+			name:      "condition already on the cached route makes no call",
+			ingress:   []routev1.RouteIngress{{RouterName: controllerRouterName, Conditions: []routev1.RouteIngressCondition{notFound}}},
+			condition: &notFound,
+		},
+		{
+			name: "recovered route without the condition makes no call",
+		},
+		{
+			name:      "condition of another router is left alone",
+			ingress:   []routev1.RouteIngress{{RouterName: "default", Conditions: []routev1.RouteIngressCondition{notFound}}},
+			condition: &notFound,
+			// the controller writes its own entry next to the router's
+			expectVerbs: []string{"get", "update"},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			route := fakeRoute("sandbox", "route", "tls")
+			route.Status.Ingress = s.ingress
+			fakeRouteClient := fakeroutev1client.NewSimpleClientset(route)
+			c := &Controller{routeClient: fakeRouteClient, routerName: controllerRouterName}
+
+			if err := c.updateExternalCertificateCondition(route, s.condition); err != nil {
+				t.Fatal(err)
+			}
+
+			var verbs []string
+			for _, action := range fakeRouteClient.Actions() {
+				verbs = append(verbs, action.GetVerb())
+			}
+			if fmt.Sprint(verbs) != fmt.Sprint(s.expectVerbs) {
+				t.Errorf("expected calls %v, got %v", s.expectVerbs, verbs)
+			}
+		})
+	}
+}
+
+func TestRouteControllerStatus(t *testing.T) {
+	var (
+		namespace = "sandbox"
+		routeName = "route"
+	)
+
+	opaque := fakeSecret(namespace, "tls")
+	opaque.Type = corev1.SecretTypeOpaque
+
+	scenarios := []struct {
+		name      string
+		secrets   []runtime.Object
+		forbidden bool
+		// host overrides the host of the route
+		host         string
+		expectReason string
+		// recover makes the secret servable, nil if it can't be recovered
+		recover func(*fake.Clientset) error
+	}{
+		{
+			name:         "missing secret",
+			expectReason: reasonSecretNotFound,
+			recover: func(c *fake.Clientset) error {
+				_, err := c.CoreV1().Secrets(namespace).Create(context.TODO(), fakeSecret(namespace, "tls"), metav1.CreateOptions{})
+				return err
+			},
+		},
+		{
+			name:         "secret of the wrong type",
+			secrets:      []runtime.Object{opaque},
+			expectReason: string(validation.InvalidSecretType),
+			// the type of a secret is immutable, so it is re-created
+			recover: func(c *fake.Clientset) error {
+				if err := c.CoreV1().Secrets(namespace).Delete(context.TODO(), "tls", metav1.DeleteOptions{}); err != nil {
+					return err
+				}
+				_, err := c.CoreV1().Secrets(namespace).Create(context.TODO(), fakeSecret(namespace, "tls"), metav1.CreateOptions{})
+				return err
+			},
+		},
+		{
+			name:         "certificate not covering the host",
+			secrets:      []runtime.Object{fakeSecret(namespace, "tls")},
+			host:         "route.example.org",
+			expectReason: string(validation.HostNotCovered),
+		},
+		{
+			name:         "forbidden secret",
+			secrets:      []runtime.Object{fakeSecret(namespace, "tls")},
+			forbidden:    true,
+			expectReason: reasonSecretForbidden,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			fakeKubeClient := fake.NewSimpleClientset(s.secrets...)
+			if s.forbidden {
+				fakeKubeClient.PrependReactor("list", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewForbidden(corev1.Resource("secrets"), "tls", fmt.Errorf("denied"))
+				})
+			}
+			route := fakeRoute(namespace, routeName, "tls")
+			if s.host != "" {
+				route.Spec.Host = s.host
+			}
+			fakeRouteClient := fakeroutev1client.NewSimpleClientset(route)
+			c := newRouteController(fakeKubeClient, fakeRouteClient, namespace)
+
+			stop := make(chan struct{})
+			defer close(stop)
+			go c.Run(1, stop)
+
+			waitForReason := func(expectReason string) {
+				t.Helper()
+				var route *routev1.Route
+				err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+					var err error
+					route, err = fakeRouteClient.RouteV1().Routes(namespace).Get(context.TODO(), routeName, metav1.GetOptions{})
+					if err != nil {
+						return false, err
+					}
+					return externalCertificateReason(route) == expectReason, nil
+				})
+				if err != nil {
+					t.Fatalf("expected condition reason %q, got status %+v", expectReason, route.Status)
+				}
+			}
+
+			waitForReason(s.expectReason)
+			if s.recover == nil {
+				return
+			}
+			if err := s.recover(fakeKubeClient); err != nil {
+				t.Fatal(err)
+			}
+			waitForReason("")
+			waitForRouteSecret(t, c, namespace, routeName, "tls")
+		})
+	}
+}
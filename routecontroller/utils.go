@@ -1,29 +1,49 @@
 package main
 
 import (
-	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/fields"
-	"k8s.io/client-go/kubernetes"
+	"context"
+
+	routev1 "github.com/openshift/api/route/v1"
+	routev1client "github.com/openshift/client-go/route/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 )
 
-func emptyResource() *v1.ConfigMap {
-	return &v1.ConfigMap{}
+func emptyResource() *routev1.Route {
+	return &routev1.Route{}
 }
-func getResource(obj interface{}) *v1.ConfigMap {
-	return obj.(*v1.ConfigMap)
+
+// getResource returns the route of an informer notification, which may be a tombstone.
+func getResource(obj interface{}) *routev1.Route {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	return obj.(*routev1.Route)
 }
 
-func getListWatcher(clientset *kubernetes.Clientset, namespace string) *cache.ListWatch {
-	// routeListWatcher := cache.NewListWatchFromClient(routev1client.NewForConfigOrDie(config).RouteV1().RESTClient(), "routes", namespace, fields.Everything())
-	return cache.NewListWatchFromClient(clientset.CoreV1().RESTClient(), "configmaps", namespace, fields.Everything())
+// getListWatcher lists and watches the routes of the namespace through the typed client,
+// so that a fake route clientset can be used in tests.
+func getListWatcher(routeClient routev1client.Interface, namespace string) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return routeClient.RouteV1().Routes(namespace).List(context.TODO(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return routeClient.RouteV1().Routes(namespace).Watch(context.TODO(), options)
+		},
+	}
 }
 
-func getReferenceSecret(route *v1.ConfigMap /*routev1.Route*/) string {
-	// route.Spec.TLS.ExternalCertificate.Name
-	// secretName := route.Spec.Containers[0].Env[0].ValueFrom.SecretKeyRef.Name
-	secretName := route.Data["secretName"]
+// getReferenceSecret returns the name of the secret referenced by route.Spec.TLS.ExternalCertificate,
+// or an empty string if the route doesn't reference a secret.
+func getReferenceSecret(route *routev1.Route) string {
+	if route.Spec.TLS == nil {
+		return ""
+	}
+	secretName := route.Spec.TLS.ExternalCertificate.Name
 	klog.Info("Referenced secretName: ", secretName)
 	return secretName
 }
@@ -0,0 +1,181 @@
+// Package validation checks that a secret referenced by a route's external certificate
+// holds a usable TLS certificate and private key for the route.
+package validation
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Reason describes why a secret can't be served for a route.
+type Reason string
+
+const (
+	// InvalidSecretType means the secret is not of type kubernetes.io/tls.
+	InvalidSecretType Reason = "InvalidSecretType"
+	// MissingCertificate means tls.crt is missing, empty, or holds no certificate.
+	MissingCertificate Reason = "MissingCertificate"
+	// MissingPrivateKey means tls.key is missing, empty, or holds no private key.
+	MissingPrivateKey Reason = "MissingPrivateKey"
+	// MalformedPEM means a PEM block of tls.crt or tls.key does not parse, or is of an unknown type.
+	MalformedPEM Reason = "MalformedPEM"
+	// KeyMismatch means the private key does not belong to the leaf certificate.
+	KeyMismatch Reason = "KeyMismatch"
+	// HostNotCovered means the SANs of the leaf certificate don't cover the route's host.
+	HostNotCovered Reason = "HostNotCovered"
+)
+
+// Error is returned when a secret fails validation.
+type Error struct {
+	// Reason is why the secret failed validation.
+	Reason Reason
+	// Message is a human readable description of the failure.
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Reason, e.Message)
+}
+
+// ReasonFor returns the Reason of err, or "" if err is not a validation Error.
+func ReasonFor(err error) Reason {
+	var validationErr *Error
+	if errors.As(err, &validationErr) {
+		return validationErr.Reason
+	}
+	return ""
+}
+
+func newError(reason Reason, format string, args ...interface{}) *Error {
+	return &Error{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+// ValidateRouteSecret checks that the secret can serve the route: it must be of type kubernetes.io/tls,
+// tls.crt and tls.key must only hold valid PEM blocks, the private key must match the leaf certificate,
+// and the SANs of the leaf certificate must cover route.Spec.Host, or all of its subdomains if the
+// route's wildcardPolicy is Subdomain. The returned error is an *Error.
+func ValidateRouteSecret(secret *corev1.Secret, route *routev1.Route) error {
+	if secret.Type != corev1.SecretTypeTLS {
+		return newError(InvalidSecretType, "secret %s is of type %q, expected %q", secret.Name, secret.Type, corev1.SecretTypeTLS)
+	}
+
+	certBlocks, err := decodeBlocks(secret.Data[corev1.TLSCertKey], corev1.TLSCertKey, MissingCertificate)
+	if err != nil {
+		return err
+	}
+	keyBlocks, err := decodeBlocks(secret.Data[corev1.TLSPrivateKeyKey], corev1.TLSPrivateKeyKey, MissingPrivateKey)
+	if err != nil {
+		return err
+	}
+
+	leaf, err := leafCertificate(certBlocks)
+	if err != nil {
+		return err
+	}
+	key, err := privateKey(keyBlocks)
+	if err != nil {
+		return err
+	}
+
+	if err := verifyKeyPair(leaf, key); err != nil {
+		return err
+	}
+	return verifyHost(leaf, route.Spec.Host, route.Spec.WildcardPolicy)
+}
+
+// decodeBlocks decodes all PEM blocks of data, the value of dataKey, and verifies them with the
+// known block decoders. Empty data is reported with missingReason.
+func decodeBlocks(data []byte, dataKey string, missingReason Reason) ([]*pem.Block, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, newError(missingReason, "%s is missing or empty", dataKey)
+	}
+
+	var blocks []*pem.Block
+	for rest := data; len(bytes.TrimSpace(rest)) > 0; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, newError(MalformedPEM, "%s holds data which is not PEM encoded", dataKey)
+		}
+		verifier, ok := knownBlockDecoders[block.Type]
+		if !ok {
+			return nil, newError(MalformedPEM, "%s holds a PEM block of unknown type %q", dataKey, block.Type)
+		}
+		if _, err := verifier(block); err != nil {
+			return nil, newError(MalformedPEM, "%s holds an invalid %s block: %v", dataKey, block.Type, err)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// leafCertificate returns the first certificate of the blocks, which is the leaf of the chain.
+func leafCertificate(blocks []*pem.Block) (*x509.Certificate, error) {
+	for _, block := range blocks {
+		if block.Type == "CERTIFICATE" {
+			// verified by decodeBlocks
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+	return nil, newError(MissingCertificate, "%s holds no certificate", corev1.TLSCertKey)
+}
+
+// privateKey returns the first private key of the blocks.
+func privateKey(blocks []*pem.Block) (crypto.PrivateKey, error) {
+	for _, block := range blocks {
+		if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			// verified by decodeBlocks
+			return parsePrivateKey(block)
+		}
+	}
+	return nil, newError(MissingPrivateKey, "%s holds no private key", corev1.TLSPrivateKeyKey)
+}
+
+// verifyKeyPair checks that key is the private key of the leaf certificate.
+func verifyKeyPair(leaf *x509.Certificate, key crypto.PrivateKey) error {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return newError(KeyMismatch, "private key of type %T can't be used with a certificate", key)
+	}
+	public, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !public.Equal(leaf.PublicKey) {
+		return newError(KeyMismatch, "private key does not match the certificate of %q", leaf.Subject.CommonName)
+	}
+	return nil
+}
+
+// verifyHost checks that the SANs of the leaf certificate cover host. With the Subdomain wildcard
+// policy the route serves all subdomains of host's parent domain, so a wildcard SAN is needed.
+func verifyHost(leaf *x509.Certificate, host string, wildcardPolicy routev1.WildcardPolicyType) error {
+	if host == "" {
+		// the host is generated by the router, and can't be checked here
+		return nil
+	}
+
+	if wildcardPolicy == routev1.WildcardPolicySubdomain {
+		_, domain, found := strings.Cut(host, ".")
+		if !found {
+			return newError(HostNotCovered, "host %q has no parent domain for wildcard policy %s", host, wildcardPolicy)
+		}
+		wildcard := "*." + domain
+		for _, name := range leaf.DNSNames {
+			if strings.EqualFold(name, wildcard) {
+				return nil
+			}
+		}
+		return newError(HostNotCovered, "certificate SANs %v don't cover %q", leaf.DNSNames, wildcard)
+	}
+
+	if err := leaf.VerifyHostname(host); err != nil {
+		return newError(HostNotCovered, "certificate SANs %v don't cover %q", leaf.DNSNames, host)
+	}
+	return nil
+}
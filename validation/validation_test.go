@@ -0,0 +1,196 @@
+package validation
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// certificate returns a PEM encoded certificate for dnsNames with the public key of key, signed by itself.
+func certificate(t *testing.T, key crypto.Signer, dnsNames ...string) []byte {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func rsaKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func ecdsaKey(t *testing.T) (*ecdsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func tlsSecret(cert, key []byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "sandbox", Name: "tls"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       cert,
+			corev1.TLSPrivateKeyKey: key,
+		},
+	}
+}
+
+func route(host string, wildcardPolicy routev1.WildcardPolicyType) *routev1.Route {
+	return &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "sandbox", Name: "route"},
+		Spec: routev1.RouteSpec{
+			Host:           host,
+			WildcardPolicy: wildcardPolicy,
+		},
+	}
+}
+
+func TestValidateRouteSecret(t *testing.T) {
+	rsaPrivateKey, rsaKeyPEM := rsaKey(t)
+	ecdsaPrivateKey, ecdsaKeyPEM := ecdsaKey(t)
+	_, otherKeyPEM := ecdsaKey(t)
+	rsaCert := certificate(t, rsaPrivateKey, "www.example.com")
+	ecdsaCert := certificate(t, ecdsaPrivateKey, "www.example.com", "*.apps.example.com")
+	caCert := certificate(t, rsaPrivateKey, "ca.example.com")
+
+	opaque := tlsSecret(rsaCert, rsaKeyPEM)
+	opaque.Type = corev1.SecretTypeOpaque
+
+	scenarios := []struct {
+		name         string
+		secret       *corev1.Secret
+		route        *routev1.Route
+		expectReason Reason
+	}{
+		{
+			name:   "valid RSA key pair",
+			secret: tlsSecret(rsaCert, rsaKeyPEM),
+			route:  route("www.example.com", routev1.WildcardPolicyNone),
+		},
+		{
+			name:   "valid ECDSA key pair with a wildcard SAN",
+			secret: tlsSecret(ecdsaCert, ecdsaKeyPEM),
+			route:  route("shop.apps.example.com", routev1.WildcardPolicyNone),
+		},
+		{
+			name:   "leaf certificate followed by its chain",
+			secret: tlsSecret(append(append([]byte{}, rsaCert...), caCert...), rsaKeyPEM),
+			route:  route("www.example.com", routev1.WildcardPolicyNone),
+		},
+		{
+			name:   "host generated by the router is not checked",
+			secret: tlsSecret(rsaCert, rsaKeyPEM),
+			route:  route("", routev1.WildcardPolicyNone),
+		},
+		{
+			name:         "secret of type Opaque",
+			secret:       opaque,
+			route:        route("www.example.com", routev1.WildcardPolicyNone),
+			expectReason: InvalidSecretType,
+		},
+		{
+			name:         "missing tls.crt",
+			secret:       tlsSecret(nil, rsaKeyPEM),
+			route:        route("www.example.com", routev1.WildcardPolicyNone),
+			expectReason: MissingCertificate,
+		},
+		{
+			name:         "missing tls.key",
+			secret:       tlsSecret(rsaCert, nil),
+			route:        route("www.example.com", routev1.WildcardPolicyNone),
+			expectReason: MissingPrivateKey,
+		},
+		{
+			name:         "tls.crt without a certificate",
+			secret:       tlsSecret(rsaKeyPEM, rsaKeyPEM),
+			route:        route("www.example.com", routev1.WildcardPolicyNone),
+			expectReason: MissingCertificate,
+		},
+		{
+			name:         "tls.crt which is not PEM encoded",
+			secret:       tlsSecret([]byte("not a certificate"), rsaKeyPEM),
+			route:        route("www.example.com", routev1.WildcardPolicyNone),
+			expectReason: MalformedPEM,
+		},
+		{
+			name:         "PEM block of unknown type",
+			secret:       tlsSecret(append(append([]byte{}, rsaCert...), pem.EncodeToMemory(&pem.Block{Type: "UNKNOWN", Bytes: []byte{1}})...), rsaKeyPEM),
+			route:        route("www.example.com", routev1.WildcardPolicyNone),
+			expectReason: MalformedPEM,
+		},
+		{
+			name:         "certificate block which does not parse",
+			secret:       tlsSecret(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1, 2, 3}}), rsaKeyPEM),
+			route:        route("www.example.com", routev1.WildcardPolicyNone),
+			expectReason: MalformedPEM,
+		},
+		{
+			name:         "private key of another certificate",
+			secret:       tlsSecret(rsaCert, otherKeyPEM),
+			route:        route("www.example.com", routev1.WildcardPolicyNone),
+			expectReason: KeyMismatch,
+		},
+		{
+			name:         "host not covered by the SANs",
+			secret:       tlsSecret(rsaCert, rsaKeyPEM),
+			route:        route("shop.example.com", routev1.WildcardPolicyNone),
+			expectReason: HostNotCovered,
+		},
+		{
+			name:   "subdomain wildcard policy covered by a wildcard SAN",
+			secret: tlsSecret(ecdsaCert, ecdsaKeyPEM),
+			route:  route("shop.apps.example.com", routev1.WildcardPolicySubdomain),
+		},
+		{
+			name:         "subdomain wildcard policy needs a wildcard SAN",
+			secret:       tlsSecret(rsaCert, rsaKeyPEM),
+			route:        route("www.example.com", routev1.WildcardPolicySubdomain),
+			expectReason: HostNotCovered,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			err := ValidateRouteSecret(s.secret, s.route)
+			if reason := ReasonFor(err); reason != s.expectReason {
+				t.Errorf("expected reason %q, got %q (%v)", s.expectReason, reason, err)
+			}
+			if err != nil && s.expectReason == "" {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}